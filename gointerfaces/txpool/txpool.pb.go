@@ -25,12 +25,13 @@ const (
 type ImportResult int32
 
 const (
-	ImportResult_SUCCESS        ImportResult = 0
-	ImportResult_ALREADY_EXISTS ImportResult = 1
-	ImportResult_FEE_TOO_LOW    ImportResult = 2
-	ImportResult_STALE          ImportResult = 3
-	ImportResult_INVALID        ImportResult = 4
-	ImportResult_INTERNAL_ERROR ImportResult = 5
+	ImportResult_SUCCESS                 ImportResult = 0
+	ImportResult_ALREADY_EXISTS          ImportResult = 1
+	ImportResult_FEE_TOO_LOW             ImportResult = 2
+	ImportResult_STALE                   ImportResult = 3
+	ImportResult_INVALID                 ImportResult = 4
+	ImportResult_INTERNAL_ERROR          ImportResult = 5
+	ImportResult_REPLACEMENT_UNDERPRICED ImportResult = 6
 )
 
 // Enum value maps for ImportResult.
@@ -42,14 +43,16 @@ var (
 		3: "STALE",
 		4: "INVALID",
 		5: "INTERNAL_ERROR",
+		6: "REPLACEMENT_UNDERPRICED",
 	}
 	ImportResult_value = map[string]int32{
-		"SUCCESS":        0,
-		"ALREADY_EXISTS": 1,
-		"FEE_TOO_LOW":    2,
-		"STALE":          3,
-		"INVALID":        4,
-		"INTERNAL_ERROR": 5,
+		"SUCCESS":                 0,
+		"ALREADY_EXISTS":          1,
+		"FEE_TOO_LOW":             2,
+		"STALE":                   3,
+		"INVALID":                 4,
+		"INTERNAL_ERROR":          5,
+		"REPLACEMENT_UNDERPRICED": 6,
 	}
 )
 
@@ -497,7 +500,8 @@ type AllReply struct {
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Txs []*AllReply_Tx `protobuf:"bytes,1,rep,name=txs,proto3" json:"txs,omitempty"`
+	Txs     []*AllReply_Tx     `protobuf:"bytes,1,rep,name=txs,proto3" json:"txs,omitempty"`
+	Senders []*AllReply_Sender `protobuf:"bytes,2,rep,name=senders,proto3" json:"senders,omitempty"`
 }
 
 func (x *AllReply) Reset() {
@@ -539,14 +543,23 @@ func (x *AllReply) GetTxs() []*AllReply_Tx {
 	return nil
 }
 
+func (x *AllReply) GetSenders() []*AllReply_Sender {
+	if x != nil {
+		return x.Senders
+	}
+	return nil
+}
+
 type AllReply_Tx struct {
 	state         protoimpl.MessageState
 	sizeCache     protoimpl.SizeCache
 	unknownFields protoimpl.UnknownFields
 
-	Type   AllReply_Type `protobuf:"varint,1,opt,name=type,proto3,enum=txpool.AllReply_Type" json:"type,omitempty"`
-	Sender []byte        `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
-	RlpTx  []byte        `protobuf:"bytes,3,opt,name=rlpTx,proto3" json:"rlpTx,omitempty"`
+	Type          AllReply_Type `protobuf:"varint,1,opt,name=type,proto3,enum=txpool.AllReply_Type" json:"type,omitempty"`
+	Sender        []byte        `protobuf:"bytes,2,opt,name=sender,proto3" json:"sender,omitempty"`
+	RlpTx         []byte        `protobuf:"bytes,3,opt,name=rlpTx,proto3" json:"rlpTx,omitempty"`
+	SubPool       uint32        `protobuf:"varint,4,opt,name=subPool,proto3" json:"subPool,omitempty"`             // 1 - pending, 2 - baseFee, 3 - queued
+	SubPoolMarker uint32        `protobuf:"varint,5,opt,name=subPoolMarker,proto3" json:"subPoolMarker,omitempty"` // bits: EnoughFeeCapProtocol, NoNonceGaps, EnoughBalance, EnoughFeeCapBlock, IsLocal
 }
 
 func (x *AllReply_Tx) Reset() {
@@ -602,6 +615,91 @@ func (x *AllReply_Tx) GetRlpTx() []byte {
 	return nil
 }
 
+func (x *AllReply_Tx) GetSubPool() uint32 {
+	if x != nil {
+		return x.SubPool
+	}
+	return 0
+}
+
+func (x *AllReply_Tx) GetSubPoolMarker() uint32 {
+	if x != nil {
+		return x.SubPoolMarker
+	}
+	return 0
+}
+
+type AllReply_Sender struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Address      []byte      `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Nonce        uint64      `protobuf:"varint,2,opt,name=nonce,proto3" json:"nonce,omitempty"`               // state nonce
+	Balance      *types.H256 `protobuf:"bytes,3,opt,name=balance,proto3" json:"balance,omitempty"`            // state balance
+	PendingNonce uint64      `protobuf:"varint,4,opt,name=pendingNonce,proto3" json:"pendingNonce,omitempty"` // next nonce after contiguous run of pooled transactions
+}
+
+func (x *AllReply_Sender) Reset() {
+	*x = AllReply_Sender{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AllReply_Sender) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AllReply_Sender) ProtoMessage() {}
+
+func (x *AllReply_Sender) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AllReply_Sender.ProtoReflect.Descriptor instead.
+func (*AllReply_Sender) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_proto_rawDescGZIP(), []int{8, 1}
+}
+
+func (x *AllReply_Sender) GetAddress() []byte {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *AllReply_Sender) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+func (x *AllReply_Sender) GetBalance() *types.H256 {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *AllReply_Sender) GetPendingNonce() uint64 {
+	if x != nil {
+		return x.PendingNonce
+	}
+	return 0
+}
+
 var File_txpool_txpool_proto protoreflect.FileDescriptor
 
 var file_txpool_txpool_proto_rawDesc = []byte{
@@ -631,48 +729,65 @@ var file_txpool_txpool_proto_rawDesc = []byte{
 	0x65, 0x73, 0x74, 0x22, 0x24, 0x0a, 0x0a, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c,
 	0x79, 0x12, 0x16, 0x0a, 0x06, 0x72, 0x70, 0x6c, 0x54, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
 	0x0c, 0x52, 0x06, 0x72, 0x70, 0x6c, 0x54, 0x78, 0x73, 0x22, 0x0c, 0x0a, 0x0a, 0x41, 0x6c, 0x6c,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xb1, 0x01, 0x0a, 0x08, 0x41, 0x6c, 0x6c, 0x52,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x22, 0xab, 0x03, 0x0a, 0x08, 0x41, 0x6c, 0x6c, 0x52,
 	0x65, 0x70, 0x6c, 0x79, 0x12, 0x25, 0x0a, 0x03, 0x74, 0x78, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28,
 	0x0b, 0x32, 0x13, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65,
-	0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x52, 0x03, 0x74, 0x78, 0x73, 0x1a, 0x5d, 0x0a, 0x02, 0x54,
-	0x78, 0x12, 0x29, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0e, 0x32,
-	0x15, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65, 0x12, 0x16, 0x0a, 0x06,
-	0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x73, 0x65,
-	0x6e, 0x64, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6c, 0x70, 0x54, 0x78, 0x18, 0x03, 0x20,
-	0x01, 0x28, 0x0c, 0x52, 0x05, 0x72, 0x6c, 0x70, 0x54, 0x78, 0x22, 0x1f, 0x0a, 0x04, 0x54, 0x79,
-	0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07, 0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12,
-	0x0a, 0x0a, 0x06, 0x51, 0x55, 0x45, 0x55, 0x45, 0x44, 0x10, 0x01, 0x2a, 0x6c, 0x0a, 0x0c, 0x49,
-	0x6d, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x0b, 0x0a, 0x07, 0x53,
-	0x55, 0x43, 0x43, 0x45, 0x53, 0x53, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x41, 0x4c, 0x52, 0x45,
-	0x41, 0x44, 0x59, 0x5f, 0x45, 0x58, 0x49, 0x53, 0x54, 0x53, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b,
-	0x46, 0x45, 0x45, 0x5f, 0x54, 0x4f, 0x4f, 0x5f, 0x4c, 0x4f, 0x57, 0x10, 0x02, 0x12, 0x09, 0x0a,
-	0x05, 0x53, 0x54, 0x41, 0x4c, 0x45, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07, 0x49, 0x4e, 0x56, 0x41,
-	0x4c, 0x49, 0x44, 0x10, 0x04, 0x12, 0x12, 0x0a, 0x0e, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x4e, 0x41,
-	0x4c, 0x5f, 0x45, 0x52, 0x52, 0x4f, 0x52, 0x10, 0x05, 0x32, 0xca, 0x02, 0x0a, 0x06, 0x54, 0x78,
-	0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x36, 0x0a, 0x07, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12,
-	0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75,
-	0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a, 0x13, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e,
-	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x31, 0x0a, 0x0b,
-	0x46, 0x69, 0x6e, 0x64, 0x55, 0x6e, 0x6b, 0x6e, 0x6f, 0x77, 0x6e, 0x12, 0x10, 0x2e, 0x74, 0x78,
-	0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x1a, 0x10, 0x2e,
-	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12,
-	0x2b, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x12, 0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e,
-	0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70,
-	0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x46, 0x0a, 0x0c,
-	0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1b, 0x2e, 0x74,
-	0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f,
-	0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e, 0x74, 0x78, 0x70, 0x6f,
-	0x6f, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x12, 0x2b, 0x0a, 0x03, 0x41, 0x6c, 0x6c, 0x12, 0x12, 0x2e, 0x74, 0x78,
-	0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c,
-	0x79, 0x12, 0x33, 0x0a, 0x05, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x12, 0x14, 0x2e, 0x74, 0x78, 0x70,
-	0x6f, 0x6f, 0x6c, 0x2e, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x1a, 0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52,
-	0x65, 0x70, 0x6c, 0x79, 0x30, 0x01, 0x42, 0x11, 0x5a, 0x0f, 0x2e, 0x2f, 0x74, 0x78, 0x70, 0x6f,
-	0x6f, 0x6c, 0x3b, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f,
-	0x33,
+	0x70, 0x6c, 0x79, 0x2e, 0x54, 0x78, 0x52, 0x03, 0x74, 0x78, 0x73, 0x12, 0x31, 0x0a, 0x07, 0x73,
+	0x65, 0x6e, 0x64, 0x65, 0x72, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x17, 0x2e, 0x74,
+	0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x53,
+	0x65, 0x6e, 0x64, 0x65, 0x72, 0x52, 0x07, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x73, 0x1a, 0x9d,
+	0x01, 0x0a, 0x02, 0x54, 0x78, 0x12, 0x29, 0x0a, 0x04, 0x74, 0x79, 0x70, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x0e, 0x32, 0x15, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c,
+	0x52, 0x65, 0x70, 0x6c, 0x79, 0x2e, 0x54, 0x79, 0x70, 0x65, 0x52, 0x04, 0x74, 0x79, 0x70, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0c,
+	0x52, 0x06, 0x73, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x14, 0x0a, 0x05, 0x72, 0x6c, 0x70, 0x54,
+	0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x05, 0x72, 0x6c, 0x70, 0x54, 0x78, 0x12, 0x18,
+	0x0a, 0x07, 0x73, 0x75, 0x62, 0x50, 0x6f, 0x6f, 0x6c, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x07, 0x73, 0x75, 0x62, 0x50, 0x6f, 0x6f, 0x6c, 0x12, 0x24, 0x0a, 0x0d, 0x73, 0x75, 0x62, 0x50,
+	0x6f, 0x6f, 0x6c, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x72, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x0d, 0x73, 0x75, 0x62, 0x50, 0x6f, 0x6f, 0x6c, 0x4d, 0x61, 0x72, 0x6b, 0x65, 0x72, 0x1a, 0x83,
+	0x01, 0x0a, 0x06, 0x53, 0x65, 0x6e, 0x64, 0x65, 0x72, 0x12, 0x18, 0x0a, 0x07, 0x61, 0x64, 0x64,
+	0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72,
+	0x65, 0x73, 0x73, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01,
+	0x28, 0x04, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x12, 0x25, 0x0a, 0x07, 0x62, 0x61, 0x6c,
+	0x61, 0x6e, 0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70,
+	0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x07, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65,
+	0x12, 0x22, 0x0a, 0x0c, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x4e, 0x6f, 0x6e, 0x63, 0x65,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0c, 0x70, 0x65, 0x6e, 0x64, 0x69, 0x6e, 0x67, 0x4e,
+	0x6f, 0x6e, 0x63, 0x65, 0x22, 0x1f, 0x0a, 0x04, 0x54, 0x79, 0x70, 0x65, 0x12, 0x0b, 0x0a, 0x07,
+	0x50, 0x45, 0x4e, 0x44, 0x49, 0x4e, 0x47, 0x10, 0x00, 0x12, 0x0a, 0x0a, 0x06, 0x51, 0x55, 0x45,
+	0x55, 0x45, 0x44, 0x10, 0x01, 0x2a, 0x89, 0x01, 0x0a, 0x0c, 0x49, 0x6d, 0x70, 0x6f, 0x72, 0x74,
+	0x52, 0x65, 0x73, 0x75, 0x6c, 0x74, 0x12, 0x0b, 0x0a, 0x07, 0x53, 0x55, 0x43, 0x43, 0x45, 0x53,
+	0x53, 0x10, 0x00, 0x12, 0x12, 0x0a, 0x0e, 0x41, 0x4c, 0x52, 0x45, 0x41, 0x44, 0x59, 0x5f, 0x45,
+	0x58, 0x49, 0x53, 0x54, 0x53, 0x10, 0x01, 0x12, 0x0f, 0x0a, 0x0b, 0x46, 0x45, 0x45, 0x5f, 0x54,
+	0x4f, 0x4f, 0x5f, 0x4c, 0x4f, 0x57, 0x10, 0x02, 0x12, 0x09, 0x0a, 0x05, 0x53, 0x54, 0x41, 0x4c,
+	0x45, 0x10, 0x03, 0x12, 0x0b, 0x0a, 0x07, 0x49, 0x4e, 0x56, 0x41, 0x4c, 0x49, 0x44, 0x10, 0x04,
+	0x12, 0x12, 0x0a, 0x0e, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x4e, 0x41, 0x4c, 0x5f, 0x45, 0x52, 0x52,
+	0x4f, 0x52, 0x10, 0x05, 0x12, 0x1b, 0x0a, 0x17, 0x52, 0x45, 0x50, 0x4c, 0x41, 0x43, 0x45, 0x4d,
+	0x45, 0x4e, 0x54, 0x5f, 0x55, 0x4e, 0x44, 0x45, 0x52, 0x50, 0x52, 0x49, 0x43, 0x45, 0x44, 0x10,
+	0x06, 0x32, 0xca, 0x02, 0x0a, 0x06, 0x54, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x12, 0x36, 0x0a, 0x07,
+	0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x16, 0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65,
+	0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x1a,
+	0x13, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x56, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x12, 0x31, 0x0a, 0x0b, 0x46, 0x69, 0x6e, 0x64, 0x55, 0x6e, 0x6b, 0x6e,
+	0x6f, 0x77, 0x6e, 0x12, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x78, 0x48,
+	0x61, 0x73, 0x68, 0x65, 0x73, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54,
+	0x78, 0x48, 0x61, 0x73, 0x68, 0x65, 0x73, 0x12, 0x2b, 0x0a, 0x03, 0x41, 0x64, 0x64, 0x12, 0x12,
+	0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x64, 0x64, 0x52,
+	0x65, 0x70, 0x6c, 0x79, 0x12, 0x46, 0x0a, 0x0c, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61, 0x63, 0x74,
+	0x69, 0x6f, 0x6e, 0x73, 0x12, 0x1b, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73,
+	0x74, 0x1a, 0x19, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x54, 0x72, 0x61, 0x6e, 0x73,
+	0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x2b, 0x0a, 0x03,
+	0x41, 0x6c, 0x6c, 0x12, 0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x41, 0x6c, 0x6c,
+	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x10, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c,
+	0x2e, 0x41, 0x6c, 0x6c, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x33, 0x0a, 0x05, 0x4f, 0x6e, 0x41,
+	0x64, 0x64, 0x12, 0x14, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2e, 0x4f, 0x6e, 0x41, 0x64,
+	0x64, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x12, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f,
+	0x6c, 0x2e, 0x4f, 0x6e, 0x41, 0x64, 0x64, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x30, 0x01, 0x42, 0x11,
+	0x5a, 0x0f, 0x2e, 0x2f, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x3b, 0x74, 0x78, 0x70, 0x6f, 0x6f,
+	0x6c, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -688,7 +803,7 @@ func file_txpool_txpool_proto_rawDescGZIP() []byte {
 }
 
 var file_txpool_txpool_proto_enumTypes = make([]protoimpl.EnumInfo, 2)
-var file_txpool_txpool_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_txpool_txpool_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
 var file_txpool_txpool_proto_goTypes = []interface{}{
 	(ImportResult)(0),           // 0: txpool.ImportResult
 	(AllReply_Type)(0),          // 1: txpool.AllReply.Type
@@ -702,33 +817,36 @@ var file_txpool_txpool_proto_goTypes = []interface{}{
 	(*AllRequest)(nil),          // 9: txpool.AllRequest
 	(*AllReply)(nil),            // 10: txpool.AllReply
 	(*AllReply_Tx)(nil),         // 11: txpool.AllReply.Tx
-	(*types.H256)(nil),          // 12: types.H256
-	(*emptypb.Empty)(nil),       // 13: google.protobuf.Empty
-	(*types.VersionReply)(nil),  // 14: types.VersionReply
+	(*AllReply_Sender)(nil),     // 12: txpool.AllReply.Sender
+	(*types.H256)(nil),          // 13: types.H256
+	(*emptypb.Empty)(nil),       // 14: google.protobuf.Empty
+	(*types.VersionReply)(nil),  // 15: types.VersionReply
 }
 var file_txpool_txpool_proto_depIdxs = []int32{
-	12, // 0: txpool.TxHashes.hashes:type_name -> types.H256
+	13, // 0: txpool.TxHashes.hashes:type_name -> types.H256
 	0,  // 1: txpool.AddReply.imported:type_name -> txpool.ImportResult
-	12, // 2: txpool.TransactionsRequest.hashes:type_name -> types.H256
+	13, // 2: txpool.TransactionsRequest.hashes:type_name -> types.H256
 	11, // 3: txpool.AllReply.txs:type_name -> txpool.AllReply.Tx
-	1,  // 4: txpool.AllReply.Tx.type:type_name -> txpool.AllReply.Type
-	13, // 5: txpool.Txpool.Version:input_type -> google.protobuf.Empty
-	2,  // 6: txpool.Txpool.FindUnknown:input_type -> txpool.TxHashes
-	3,  // 7: txpool.Txpool.Add:input_type -> txpool.AddRequest
-	5,  // 8: txpool.Txpool.Transactions:input_type -> txpool.TransactionsRequest
-	9,  // 9: txpool.Txpool.All:input_type -> txpool.AllRequest
-	7,  // 10: txpool.Txpool.OnAdd:input_type -> txpool.OnAddRequest
-	14, // 11: txpool.Txpool.Version:output_type -> types.VersionReply
-	2,  // 12: txpool.Txpool.FindUnknown:output_type -> txpool.TxHashes
-	4,  // 13: txpool.Txpool.Add:output_type -> txpool.AddReply
-	6,  // 14: txpool.Txpool.Transactions:output_type -> txpool.TransactionsReply
-	10, // 15: txpool.Txpool.All:output_type -> txpool.AllReply
-	8,  // 16: txpool.Txpool.OnAdd:output_type -> txpool.OnAddReply
-	11, // [11:17] is the sub-list for method output_type
-	5,  // [5:11] is the sub-list for method input_type
-	5,  // [5:5] is the sub-list for extension type_name
-	5,  // [5:5] is the sub-list for extension extendee
-	0,  // [0:5] is the sub-list for field type_name
+	12, // 4: txpool.AllReply.senders:type_name -> txpool.AllReply.Sender
+	1,  // 5: txpool.AllReply.Tx.type:type_name -> txpool.AllReply.Type
+	13, // 6: txpool.AllReply.Sender.balance:type_name -> types.H256
+	14, // 7: txpool.Txpool.Version:input_type -> google.protobuf.Empty
+	2,  // 8: txpool.Txpool.FindUnknown:input_type -> txpool.TxHashes
+	3,  // 9: txpool.Txpool.Add:input_type -> txpool.AddRequest
+	5,  // 10: txpool.Txpool.Transactions:input_type -> txpool.TransactionsRequest
+	9,  // 11: txpool.Txpool.All:input_type -> txpool.AllRequest
+	7,  // 12: txpool.Txpool.OnAdd:input_type -> txpool.OnAddRequest
+	15, // 13: txpool.Txpool.Version:output_type -> types.VersionReply
+	2,  // 14: txpool.Txpool.FindUnknown:output_type -> txpool.TxHashes
+	4,  // 15: txpool.Txpool.Add:output_type -> txpool.AddReply
+	6,  // 16: txpool.Txpool.Transactions:output_type -> txpool.TransactionsReply
+	10, // 17: txpool.Txpool.All:output_type -> txpool.AllReply
+	8,  // 18: txpool.Txpool.OnAdd:output_type -> txpool.OnAddReply
+	13, // [13:19] is the sub-list for method output_type
+	7,  // [7:13] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
 }
 
 func init() { file_txpool_txpool_proto_init() }
@@ -857,6 +975,18 @@ func file_txpool_txpool_proto_init() {
 				return nil
 			}
 		}
+		file_txpool_txpool_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AllReply_Sender); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -864,7 +994,7 @@ func file_txpool_txpool_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_txpool_txpool_proto_rawDesc,
 			NumEnums:      2,
-			NumMessages:   10,
+			NumMessages:   11,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
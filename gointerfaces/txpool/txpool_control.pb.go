@@ -0,0 +1,839 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.27.1
+// 	protoc        v3.17.3
+// source: txpool/txpool_control.proto
+
+package txpool
+
+import (
+	types "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AccountInfoRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	BlockHash *types.H256 `protobuf:"bytes,1,opt,name=block_hash,json=blockHash,proto3" json:"block_hash,omitempty"`
+	Account   *types.H160 `protobuf:"bytes,2,opt,name=account,proto3" json:"account,omitempty"`
+}
+
+func (x *AccountInfoRequest) Reset() {
+	*x = AccountInfoRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_control_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AccountInfoRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountInfoRequest) ProtoMessage() {}
+
+func (x *AccountInfoRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_control_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountInfoRequest.ProtoReflect.Descriptor instead.
+func (*AccountInfoRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_control_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AccountInfoRequest) GetBlockHash() *types.H256 {
+	if x != nil {
+		return x.BlockHash
+	}
+	return nil
+}
+
+func (x *AccountInfoRequest) GetAccount() *types.H160 {
+	if x != nil {
+		return x.Account
+	}
+	return nil
+}
+
+type AccountInfoReply struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Balance *types.H256 `protobuf:"bytes,1,opt,name=balance,proto3" json:"balance,omitempty"`
+	Nonce   uint64      `protobuf:"varint,2,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (x *AccountInfoReply) Reset() {
+	*x = AccountInfoReply{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_control_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AccountInfoReply) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountInfoReply) ProtoMessage() {}
+
+func (x *AccountInfoReply) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_control_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountInfoReply.ProtoReflect.Descriptor instead.
+func (*AccountInfoReply) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_control_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *AccountInfoReply) GetBalance() *types.H256 {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *AccountInfoReply) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+type BlockStreamRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to StartWith:
+	//	*BlockStreamRequest_Latest
+	//	*BlockStreamRequest_BlockHash
+	StartWith isBlockStreamRequest_StartWith `protobuf_oneof:"start_with"`
+}
+
+func (x *BlockStreamRequest) Reset() {
+	*x = BlockStreamRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_control_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockStreamRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockStreamRequest) ProtoMessage() {}
+
+func (x *BlockStreamRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_control_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockStreamRequest.ProtoReflect.Descriptor instead.
+func (*BlockStreamRequest) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_control_proto_rawDescGZIP(), []int{2}
+}
+
+func (m *BlockStreamRequest) GetStartWith() isBlockStreamRequest_StartWith {
+	if m != nil {
+		return m.StartWith
+	}
+	return nil
+}
+
+func (x *BlockStreamRequest) GetLatest() *emptypb.Empty {
+	if x, ok := x.GetStartWith().(*BlockStreamRequest_Latest); ok {
+		return x.Latest
+	}
+	return nil
+}
+
+func (x *BlockStreamRequest) GetBlockHash() *types.H256 {
+	if x, ok := x.GetStartWith().(*BlockStreamRequest_BlockHash); ok {
+		return x.BlockHash
+	}
+	return nil
+}
+
+type isBlockStreamRequest_StartWith interface {
+	isBlockStreamRequest_StartWith()
+}
+
+type BlockStreamRequest_Latest struct {
+	Latest *emptypb.Empty `protobuf:"bytes,1,opt,name=latest,proto3,oneof"`
+}
+
+type BlockStreamRequest_BlockHash struct {
+	BlockHash *types.H256 `protobuf:"bytes,2,opt,name=block_hash,json=blockHash,proto3,oneof"`
+}
+
+func (*BlockStreamRequest_Latest) isBlockStreamRequest_StartWith() {}
+
+func (*BlockStreamRequest_BlockHash) isBlockStreamRequest_StartWith() {}
+
+type AccountInfo struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Address *types.H160 `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Balance *types.H256 `protobuf:"bytes,2,opt,name=balance,proto3" json:"balance,omitempty"`
+	Nonce   uint64      `protobuf:"varint,3,opt,name=nonce,proto3" json:"nonce,omitempty"`
+}
+
+func (x *AccountInfo) Reset() {
+	*x = AccountInfo{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_control_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AccountInfo) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountInfo) ProtoMessage() {}
+
+func (x *AccountInfo) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_control_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountInfo.ProtoReflect.Descriptor instead.
+func (*AccountInfo) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_control_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *AccountInfo) GetAddress() *types.H160 {
+	if x != nil {
+		return x.Address
+	}
+	return nil
+}
+
+func (x *AccountInfo) GetBalance() *types.H256 {
+	if x != nil {
+		return x.Balance
+	}
+	return nil
+}
+
+func (x *AccountInfo) GetNonce() uint64 {
+	if x != nil {
+		return x.Nonce
+	}
+	return 0
+}
+
+type AppliedBlock struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Hash            *types.H256    `protobuf:"bytes,1,opt,name=hash,proto3" json:"hash,omitempty"`
+	ParentHash      *types.H256    `protobuf:"bytes,2,opt,name=parent_hash,json=parentHash,proto3" json:"parent_hash,omitempty"`
+	ChangedAccounts []*AccountInfo `protobuf:"bytes,3,rep,name=changed_accounts,json=changedAccounts,proto3" json:"changed_accounts,omitempty"`
+	BlockHeight     uint64         `protobuf:"varint,4,opt,name=block_height,json=blockHeight,proto3" json:"block_height,omitempty"`
+	GasUsed         uint64         `protobuf:"varint,5,opt,name=gas_used,json=gasUsed,proto3" json:"gas_used,omitempty"`
+	GasLimit        uint64         `protobuf:"varint,6,opt,name=gas_limit,json=gasLimit,proto3" json:"gas_limit,omitempty"`
+	BaseFee         uint64         `protobuf:"varint,7,opt,name=base_fee,json=baseFee,proto3" json:"base_fee,omitempty"` // zero before London
+}
+
+func (x *AppliedBlock) Reset() {
+	*x = AppliedBlock{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_control_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *AppliedBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AppliedBlock) ProtoMessage() {}
+
+func (x *AppliedBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_control_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AppliedBlock.ProtoReflect.Descriptor instead.
+func (*AppliedBlock) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_control_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *AppliedBlock) GetHash() *types.H256 {
+	if x != nil {
+		return x.Hash
+	}
+	return nil
+}
+
+func (x *AppliedBlock) GetParentHash() *types.H256 {
+	if x != nil {
+		return x.ParentHash
+	}
+	return nil
+}
+
+func (x *AppliedBlock) GetChangedAccounts() []*AccountInfo {
+	if x != nil {
+		return x.ChangedAccounts
+	}
+	return nil
+}
+
+func (x *AppliedBlock) GetBlockHeight() uint64 {
+	if x != nil {
+		return x.BlockHeight
+	}
+	return 0
+}
+
+func (x *AppliedBlock) GetGasUsed() uint64 {
+	if x != nil {
+		return x.GasUsed
+	}
+	return 0
+}
+
+func (x *AppliedBlock) GetGasLimit() uint64 {
+	if x != nil {
+		return x.GasLimit
+	}
+	return 0
+}
+
+func (x *AppliedBlock) GetBaseFee() uint64 {
+	if x != nil {
+		return x.BaseFee
+	}
+	return 0
+}
+
+type RevertedBlock struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	RevertedHash         *types.H256    `protobuf:"bytes,1,opt,name=reverted_hash,json=revertedHash,proto3" json:"reverted_hash,omitempty"`
+	RevertedTransactions [][]byte       `protobuf:"bytes,2,rep,name=reverted_transactions,json=revertedTransactions,proto3" json:"reverted_transactions,omitempty"`
+	NewHash              *types.H256    `protobuf:"bytes,3,opt,name=new_hash,json=newHash,proto3" json:"new_hash,omitempty"`
+	NewParent            *types.H256    `protobuf:"bytes,4,opt,name=new_parent,json=newParent,proto3" json:"new_parent,omitempty"`
+	RevertedAccounts     []*AccountInfo `protobuf:"bytes,5,rep,name=reverted_accounts,json=revertedAccounts,proto3" json:"reverted_accounts,omitempty"`
+	NewBlockHeight       uint64         `protobuf:"varint,6,opt,name=new_block_height,json=newBlockHeight,proto3" json:"new_block_height,omitempty"` // height of new_hash
+	NewGasUsed           uint64         `protobuf:"varint,7,opt,name=new_gas_used,json=newGasUsed,proto3" json:"new_gas_used,omitempty"`             // header fields of new_hash
+	NewGasLimit          uint64         `protobuf:"varint,8,opt,name=new_gas_limit,json=newGasLimit,proto3" json:"new_gas_limit,omitempty"`
+	NewBaseFee           uint64         `protobuf:"varint,9,opt,name=new_base_fee,json=newBaseFee,proto3" json:"new_base_fee,omitempty"` // zero before London
+}
+
+func (x *RevertedBlock) Reset() {
+	*x = RevertedBlock{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_control_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RevertedBlock) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevertedBlock) ProtoMessage() {}
+
+func (x *RevertedBlock) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_control_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevertedBlock.ProtoReflect.Descriptor instead.
+func (*RevertedBlock) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_control_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *RevertedBlock) GetRevertedHash() *types.H256 {
+	if x != nil {
+		return x.RevertedHash
+	}
+	return nil
+}
+
+func (x *RevertedBlock) GetRevertedTransactions() [][]byte {
+	if x != nil {
+		return x.RevertedTransactions
+	}
+	return nil
+}
+
+func (x *RevertedBlock) GetNewHash() *types.H256 {
+	if x != nil {
+		return x.NewHash
+	}
+	return nil
+}
+
+func (x *RevertedBlock) GetNewParent() *types.H256 {
+	if x != nil {
+		return x.NewParent
+	}
+	return nil
+}
+
+func (x *RevertedBlock) GetRevertedAccounts() []*AccountInfo {
+	if x != nil {
+		return x.RevertedAccounts
+	}
+	return nil
+}
+
+func (x *RevertedBlock) GetNewBlockHeight() uint64 {
+	if x != nil {
+		return x.NewBlockHeight
+	}
+	return 0
+}
+
+func (x *RevertedBlock) GetNewGasUsed() uint64 {
+	if x != nil {
+		return x.NewGasUsed
+	}
+	return 0
+}
+
+func (x *RevertedBlock) GetNewGasLimit() uint64 {
+	if x != nil {
+		return x.NewGasLimit
+	}
+	return 0
+}
+
+func (x *RevertedBlock) GetNewBaseFee() uint64 {
+	if x != nil {
+		return x.NewBaseFee
+	}
+	return 0
+}
+
+type BlockDiff struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Types that are assignable to Diff:
+	//	*BlockDiff_Applied
+	//	*BlockDiff_Reverted
+	Diff isBlockDiff_Diff `protobuf_oneof:"diff"`
+}
+
+func (x *BlockDiff) Reset() {
+	*x = BlockDiff{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_txpool_txpool_control_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *BlockDiff) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlockDiff) ProtoMessage() {}
+
+func (x *BlockDiff) ProtoReflect() protoreflect.Message {
+	mi := &file_txpool_txpool_control_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlockDiff.ProtoReflect.Descriptor instead.
+func (*BlockDiff) Descriptor() ([]byte, []int) {
+	return file_txpool_txpool_control_proto_rawDescGZIP(), []int{6}
+}
+
+func (m *BlockDiff) GetDiff() isBlockDiff_Diff {
+	if m != nil {
+		return m.Diff
+	}
+	return nil
+}
+
+func (x *BlockDiff) GetApplied() *AppliedBlock {
+	if x, ok := x.GetDiff().(*BlockDiff_Applied); ok {
+		return x.Applied
+	}
+	return nil
+}
+
+func (x *BlockDiff) GetReverted() *RevertedBlock {
+	if x, ok := x.GetDiff().(*BlockDiff_Reverted); ok {
+		return x.Reverted
+	}
+	return nil
+}
+
+type isBlockDiff_Diff interface {
+	isBlockDiff_Diff()
+}
+
+type BlockDiff_Applied struct {
+	Applied *AppliedBlock `protobuf:"bytes,1,opt,name=applied,proto3,oneof"`
+}
+
+type BlockDiff_Reverted struct {
+	Reverted *RevertedBlock `protobuf:"bytes,2,opt,name=reverted,proto3,oneof"`
+}
+
+func (*BlockDiff_Applied) isBlockDiff_Diff() {}
+
+func (*BlockDiff_Reverted) isBlockDiff_Diff() {}
+
+var File_txpool_txpool_control_proto protoreflect.FileDescriptor
+
+var file_txpool_txpool_control_proto_rawDesc = []byte{
+	0x0a, 0x1b, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x2f, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x5f,
+	0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x12, 0x0e, 0x74,
+	0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x1a, 0x1b, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x65,
+	0x6d, 0x70, 0x74, 0x79, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x1a, 0x11, 0x74, 0x79, 0x70, 0x65,
+	0x73, 0x2f, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0x67, 0x0a,
+	0x12, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75,
+	0x65, 0x73, 0x74, 0x12, 0x2a, 0x0a, 0x0a, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x61, 0x73,
+	0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e,
+	0x48, 0x32, 0x35, 0x36, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x12,
+	0x25, 0x0a, 0x07, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x07, 0x61,
+	0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x22, 0x4f, 0x0a, 0x10, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x25, 0x0a, 0x07, 0x62, 0x61,
+	0x6c, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79,
+	0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x07, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63,
+	0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04,
+	0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22, 0x82, 0x01, 0x0a, 0x12, 0x42, 0x6c, 0x6f, 0x63,
+	0x6b, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x30,
+	0x0a, 0x06, 0x6c, 0x61, 0x74, 0x65, 0x73, 0x74, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x16,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x45, 0x6d, 0x70, 0x74, 0x79, 0x48, 0x00, 0x52, 0x06, 0x6c, 0x61, 0x74, 0x65, 0x73, 0x74,
+	0x12, 0x2c, 0x0a, 0x0a, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35,
+	0x36, 0x48, 0x00, 0x52, 0x09, 0x62, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x61, 0x73, 0x68, 0x42, 0x0c,
+	0x0a, 0x0a, 0x73, 0x74, 0x61, 0x72, 0x74, 0x5f, 0x77, 0x69, 0x74, 0x68, 0x22, 0x71, 0x0a, 0x0b,
+	0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x25, 0x0a, 0x07, 0x61,
+	0x64, 0x64, 0x72, 0x65, 0x73, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74,
+	0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x31, 0x36, 0x30, 0x52, 0x07, 0x61, 0x64, 0x64, 0x72, 0x65,
+	0x73, 0x73, 0x12, 0x25, 0x0a, 0x07, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36,
+	0x52, 0x07, 0x62, 0x61, 0x6c, 0x61, 0x6e, 0x63, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x6e,
+	0x63, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x05, 0x6e, 0x6f, 0x6e, 0x63, 0x65, 0x22,
+	0x9b, 0x02, 0x0a, 0x0c, 0x41, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x12, 0x1f, 0x0a, 0x04, 0x68, 0x61, 0x73, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b,
+	0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x04, 0x68, 0x61, 0x73,
+	0x68, 0x12, 0x2c, 0x0a, 0x0b, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x5f, 0x68, 0x61, 0x73, 0x68,
+	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48,
+	0x32, 0x35, 0x36, 0x52, 0x0a, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x12,
+	0x46, 0x0a, 0x10, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x5f, 0x61, 0x63, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x73, 0x18, 0x03, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x74, 0x78, 0x70, 0x6f,
+	0x6f, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75,
+	0x6e, 0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x0f, 0x63, 0x68, 0x61, 0x6e, 0x67, 0x65, 0x64, 0x41,
+	0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x12, 0x21, 0x0a, 0x0c, 0x62, 0x6c, 0x6f, 0x63, 0x6b,
+	0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x04, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x62,
+	0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x67, 0x61,
+	0x73, 0x5f, 0x75, 0x73, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x67, 0x61,
+	0x73, 0x55, 0x73, 0x65, 0x64, 0x12, 0x1b, 0x0a, 0x09, 0x67, 0x61, 0x73, 0x5f, 0x6c, 0x69, 0x6d,
+	0x69, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x67, 0x61, 0x73, 0x4c, 0x69, 0x6d,
+	0x69, 0x74, 0x12, 0x19, 0x0a, 0x08, 0x62, 0x61, 0x73, 0x65, 0x5f, 0x66, 0x65, 0x65, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x07, 0x62, 0x61, 0x73, 0x65, 0x46, 0x65, 0x65, 0x22, 0xa6, 0x03,
+	0x0a, 0x0d, 0x52, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x12,
+	0x30, 0x0a, 0x0d, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x68, 0x61, 0x73, 0x68,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48,
+	0x32, 0x35, 0x36, 0x52, 0x0c, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x48, 0x61, 0x73,
+	0x68, 0x12, 0x33, 0x0a, 0x15, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x74, 0x72,
+	0x61, 0x6e, 0x73, 0x61, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x18, 0x02, 0x20, 0x03, 0x28, 0x0c,
+	0x52, 0x14, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x54, 0x72, 0x61, 0x6e, 0x73, 0x61,
+	0x63, 0x74, 0x69, 0x6f, 0x6e, 0x73, 0x12, 0x26, 0x0a, 0x08, 0x6e, 0x65, 0x77, 0x5f, 0x68, 0x61,
+	0x73, 0x68, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73,
+	0x2e, 0x48, 0x32, 0x35, 0x36, 0x52, 0x07, 0x6e, 0x65, 0x77, 0x48, 0x61, 0x73, 0x68, 0x12, 0x2a,
+	0x0a, 0x0a, 0x6e, 0x65, 0x77, 0x5f, 0x70, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x18, 0x04, 0x20, 0x01,
+	0x28, 0x0b, 0x32, 0x0b, 0x2e, 0x74, 0x79, 0x70, 0x65, 0x73, 0x2e, 0x48, 0x32, 0x35, 0x36, 0x52,
+	0x09, 0x6e, 0x65, 0x77, 0x50, 0x61, 0x72, 0x65, 0x6e, 0x74, 0x12, 0x48, 0x0a, 0x11, 0x72, 0x65,
+	0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x5f, 0x61, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x73, 0x18,
+	0x05, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x1b, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x63,
+	0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x49, 0x6e,
+	0x66, 0x6f, 0x52, 0x10, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x41, 0x63, 0x63, 0x6f,
+	0x75, 0x6e, 0x74, 0x73, 0x12, 0x28, 0x0a, 0x10, 0x6e, 0x65, 0x77, 0x5f, 0x62, 0x6c, 0x6f, 0x63,
+	0x6b, 0x5f, 0x68, 0x65, 0x69, 0x67, 0x68, 0x74, 0x18, 0x06, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0e,
+	0x6e, 0x65, 0x77, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48, 0x65, 0x69, 0x67, 0x68, 0x74, 0x12, 0x20,
+	0x0a, 0x0c, 0x6e, 0x65, 0x77, 0x5f, 0x67, 0x61, 0x73, 0x5f, 0x75, 0x73, 0x65, 0x64, 0x18, 0x07,
+	0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x6e, 0x65, 0x77, 0x47, 0x61, 0x73, 0x55, 0x73, 0x65, 0x64,
+	0x12, 0x22, 0x0a, 0x0d, 0x6e, 0x65, 0x77, 0x5f, 0x67, 0x61, 0x73, 0x5f, 0x6c, 0x69, 0x6d, 0x69,
+	0x74, 0x18, 0x08, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0b, 0x6e, 0x65, 0x77, 0x47, 0x61, 0x73, 0x4c,
+	0x69, 0x6d, 0x69, 0x74, 0x12, 0x20, 0x0a, 0x0c, 0x6e, 0x65, 0x77, 0x5f, 0x62, 0x61, 0x73, 0x65,
+	0x5f, 0x66, 0x65, 0x65, 0x18, 0x09, 0x20, 0x01, 0x28, 0x04, 0x52, 0x0a, 0x6e, 0x65, 0x77, 0x42,
+	0x61, 0x73, 0x65, 0x46, 0x65, 0x65, 0x22, 0x8a, 0x01, 0x0a, 0x09, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x44, 0x69, 0x66, 0x66, 0x12, 0x38, 0x0a, 0x07, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1c, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x63,
+	0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x41, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x48, 0x00, 0x52, 0x07, 0x61, 0x70, 0x70, 0x6c, 0x69, 0x65, 0x64, 0x12, 0x3b,
+	0x0a, 0x08, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b,
+	0x32, 0x1d, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f,
+	0x6c, 0x2e, 0x52, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x42, 0x6c, 0x6f, 0x63, 0x6b, 0x48,
+	0x00, 0x52, 0x08, 0x72, 0x65, 0x76, 0x65, 0x72, 0x74, 0x65, 0x64, 0x42, 0x06, 0x0a, 0x04, 0x64,
+	0x69, 0x66, 0x66, 0x32, 0xb4, 0x01, 0x0a, 0x0d, 0x54, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x43, 0x6f,
+	0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x12, 0x53, 0x0a, 0x0b, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74,
+	0x49, 0x6e, 0x66, 0x6f, 0x12, 0x22, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x63, 0x6f,
+	0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e, 0x74, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x20, 0x2e, 0x74, 0x78, 0x70, 0x6f, 0x6f,
+	0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x41, 0x63, 0x63, 0x6f, 0x75, 0x6e,
+	0x74, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x12, 0x4e, 0x0a, 0x0b, 0x42, 0x6c,
+	0x6f, 0x63, 0x6b, 0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x12, 0x22, 0x2e, 0x74, 0x78, 0x70, 0x6f,
+	0x6f, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x42, 0x6c, 0x6f, 0x63, 0x6b,
+	0x53, 0x74, 0x72, 0x65, 0x61, 0x6d, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x19, 0x2e,
+	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x5f, 0x63, 0x6f, 0x6e, 0x74, 0x72, 0x6f, 0x6c, 0x2e, 0x42,
+	0x6c, 0x6f, 0x63, 0x6b, 0x44, 0x69, 0x66, 0x66, 0x30, 0x01, 0x42, 0x11, 0x5a, 0x0f, 0x2e, 0x2f,
+	0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x3b, 0x74, 0x78, 0x70, 0x6f, 0x6f, 0x6c, 0x62, 0x06, 0x70,
+	0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_txpool_txpool_control_proto_rawDescOnce sync.Once
+	file_txpool_txpool_control_proto_rawDescData = file_txpool_txpool_control_proto_rawDesc
+)
+
+func file_txpool_txpool_control_proto_rawDescGZIP() []byte {
+	file_txpool_txpool_control_proto_rawDescOnce.Do(func() {
+		file_txpool_txpool_control_proto_rawDescData = protoimpl.X.CompressGZIP(file_txpool_txpool_control_proto_rawDescData)
+	})
+	return file_txpool_txpool_control_proto_rawDescData
+}
+
+var file_txpool_txpool_control_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_txpool_txpool_control_proto_goTypes = []interface{}{
+	(*AccountInfoRequest)(nil), // 0: txpool_control.AccountInfoRequest
+	(*AccountInfoReply)(nil),   // 1: txpool_control.AccountInfoReply
+	(*BlockStreamRequest)(nil), // 2: txpool_control.BlockStreamRequest
+	(*AccountInfo)(nil),        // 3: txpool_control.AccountInfo
+	(*AppliedBlock)(nil),       // 4: txpool_control.AppliedBlock
+	(*RevertedBlock)(nil),      // 5: txpool_control.RevertedBlock
+	(*BlockDiff)(nil),          // 6: txpool_control.BlockDiff
+	(*types.H256)(nil),         // 7: types.H256
+	(*types.H160)(nil),         // 8: types.H160
+	(*emptypb.Empty)(nil),      // 9: google.protobuf.Empty
+}
+var file_txpool_txpool_control_proto_depIdxs = []int32{
+	7,  // 0: txpool_control.AccountInfoRequest.block_hash:type_name -> types.H256
+	8,  // 1: txpool_control.AccountInfoRequest.account:type_name -> types.H160
+	7,  // 2: txpool_control.AccountInfoReply.balance:type_name -> types.H256
+	9,  // 3: txpool_control.BlockStreamRequest.latest:type_name -> google.protobuf.Empty
+	7,  // 4: txpool_control.BlockStreamRequest.block_hash:type_name -> types.H256
+	8,  // 5: txpool_control.AccountInfo.address:type_name -> types.H160
+	7,  // 6: txpool_control.AccountInfo.balance:type_name -> types.H256
+	7,  // 7: txpool_control.AppliedBlock.hash:type_name -> types.H256
+	7,  // 8: txpool_control.AppliedBlock.parent_hash:type_name -> types.H256
+	3,  // 9: txpool_control.AppliedBlock.changed_accounts:type_name -> txpool_control.AccountInfo
+	7,  // 10: txpool_control.RevertedBlock.reverted_hash:type_name -> types.H256
+	7,  // 11: txpool_control.RevertedBlock.new_hash:type_name -> types.H256
+	7,  // 12: txpool_control.RevertedBlock.new_parent:type_name -> types.H256
+	3,  // 13: txpool_control.RevertedBlock.reverted_accounts:type_name -> txpool_control.AccountInfo
+	4,  // 14: txpool_control.BlockDiff.applied:type_name -> txpool_control.AppliedBlock
+	5,  // 15: txpool_control.BlockDiff.reverted:type_name -> txpool_control.RevertedBlock
+	0,  // 16: txpool_control.TxpoolControl.AccountInfo:input_type -> txpool_control.AccountInfoRequest
+	2,  // 17: txpool_control.TxpoolControl.BlockStream:input_type -> txpool_control.BlockStreamRequest
+	1,  // 18: txpool_control.TxpoolControl.AccountInfo:output_type -> txpool_control.AccountInfoReply
+	6,  // 19: txpool_control.TxpoolControl.BlockStream:output_type -> txpool_control.BlockDiff
+	18, // [18:20] is the sub-list for method output_type
+	16, // [16:18] is the sub-list for method input_type
+	16, // [16:16] is the sub-list for extension type_name
+	16, // [16:16] is the sub-list for extension extendee
+	0,  // [0:16] is the sub-list for field type_name
+}
+
+func init() { file_txpool_txpool_control_proto_init() }
+func file_txpool_txpool_control_proto_init() {
+	if File_txpool_txpool_control_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_txpool_txpool_control_proto_msgTypes[0].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AccountInfoRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_control_proto_msgTypes[1].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AccountInfoReply); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_control_proto_msgTypes[2].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockStreamRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_control_proto_msgTypes[3].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AccountInfo); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_control_proto_msgTypes[4].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*AppliedBlock); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_control_proto_msgTypes[5].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*RevertedBlock); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_txpool_txpool_control_proto_msgTypes[6].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*BlockDiff); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	file_txpool_txpool_control_proto_msgTypes[2].OneofWrappers = []interface{}{
+		(*BlockStreamRequest_Latest)(nil),
+		(*BlockStreamRequest_BlockHash)(nil),
+	}
+	file_txpool_txpool_control_proto_msgTypes[6].OneofWrappers = []interface{}{
+		(*BlockDiff_Applied)(nil),
+		(*BlockDiff_Reverted)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_txpool_txpool_control_proto_rawDesc,
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_txpool_txpool_control_proto_goTypes,
+		DependencyIndexes: file_txpool_txpool_control_proto_depIdxs,
+		MessageInfos:      file_txpool_txpool_control_proto_msgTypes,
+	}.Build()
+	File_txpool_txpool_control_proto = out.File
+	file_txpool_txpool_control_proto_rawDesc = nil
+	file_txpool_txpool_control_proto_goTypes = nil
+	file_txpool_txpool_control_proto_depIdxs = nil
+}
@@ -273,6 +273,15 @@ const (
 	PendingEpoch = "DevPendingEpoch" // block_num_u64+block_hash->transition_proof
 )
 
+// Transaction pool - stored in separated database (TxPoolDB label)
+const (
+	PoolTransaction        = "PoolTransaction"        // txn_hash -> rlp(tx) of transactions kept in the pool
+	PoolLocalTransaction   = "PoolLocalTransaction"   // txn_hash -> empty, marks local transactions of PoolTransaction
+	PoolSender             = "PoolSender"             // sender_address -> nonce_u64 + balance_u256, cache of senders state
+	RecentLocalTransaction = "RecentLocalTransaction" // txn_hash -> sender_address, local transactions which were mined - to restore isLocal flag at unwind
+	PoolInfo               = "PoolInfo"               // key -> value, last seen block and base fees
+)
+
 // Keys
 var (
 	//StorageModeTEVM - does not translate EVM to TEVM
@@ -341,7 +350,13 @@ var ChaindataTables = []string{
 	PendingEpoch,
 }
 
-var TxPoolTables = []string{}
+var TxPoolTables = []string{
+	PoolTransaction,
+	PoolLocalTransaction,
+	PoolSender,
+	RecentLocalTransaction,
+	PoolInfo,
+}
 var SentryTables = []string{}
 
 // ChaindataDeprecatedTables - list of buckets which can be programmatically deleted - for example after migration
@@ -408,6 +423,8 @@ var ChaindataTablesCfg = TableCfg{
 	},
 }
 
+var TxpoolTablesCfg = TableCfg{}
+
 func sortBuckets() {
 	sort.SliceStable(ChaindataTables, func(i, j int) bool {
 		return strings.Compare(ChaindataTables[i], ChaindataTables[j]) < 0
@@ -437,4 +454,11 @@ func reinit() {
 		tmp.IsDeprecated = true
 		ChaindataTablesCfg[name] = tmp
 	}
+
+	for _, name := range TxPoolTables {
+		_, ok := TxpoolTablesCfg[name]
+		if !ok {
+			TxpoolTablesCfg[name] = TableCfgItem{}
+		}
+	}
 }
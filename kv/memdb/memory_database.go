@@ -30,12 +30,23 @@ func New() kv.RwDB {
 	return mdbx.NewMDBX(logger).InMem().MustOpen()
 }
 
+func NewPoolDB() kv.RwDB {
+	logger := log.New() //TODO: move higher
+	return mdbx.NewMDBX(logger).InMem().Label(kv.TxPoolDB).WithTablessCfg(func(defaultBuckets kv.TableCfg) kv.TableCfg { return kv.TxpoolTablesCfg }).MustOpen()
+}
+
 func NewTestDB(t testing.TB) kv.RwDB {
 	db := New()
 	t.Cleanup(db.Close)
 	return db
 }
 
+func NewTestPoolDB(t testing.TB) kv.RwDB {
+	db := NewPoolDB()
+	t.Cleanup(db.Close)
+	return db
+}
+
 func NewTestTx(t testing.TB) (kv.RwDB, kv.RwTx) {
 	db := New()
 	t.Cleanup(db.Close)
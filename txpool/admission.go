@@ -0,0 +1,102 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+// AdmissionPolicy - decides which transactions may enter the pool: for example rejects transactions to sanctioned
+// addresses, or accepts only whitelisted senders on private network. It's consulted for every new transaction which
+// passed validation. Called under lock of the pool - so it must be fast and must not call the pool.
+// Rejected transactions get PolicyRejected reason and TxRejected event
+type AdmissionPolicy interface {
+	Admit(tx *TxSlot, sender [20]byte, isLocal bool) bool
+}
+
+// AdmissionPolicyFunc - adapter which allows to use ordinary function as AdmissionPolicy
+type AdmissionPolicyFunc func(tx *TxSlot, sender [20]byte, isLocal bool) bool
+
+func (f AdmissionPolicyFunc) Admit(tx *TxSlot, sender [20]byte, isLocal bool) bool {
+	return f(tx, sender, isLocal)
+}
+
+// CombinePolicies - admits transaction only if all policies admit it. Policies are consulted in order, until
+// first rejection. nil policies are skipped
+func CombinePolicies(policies ...AdmissionPolicy) AdmissionPolicy {
+	return AdmissionPolicyFunc(func(tx *TxSlot, sender [20]byte, isLocal bool) bool {
+		for _, policy := range policies {
+			if policy != nil && !policy.Admit(tx, sender, isLocal) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// DenyRecipients - rejects transactions to any of addrs
+func DenyRecipients(addrs ...[20]byte) AdmissionPolicy {
+	denied := addrSet(addrs)
+	return AdmissionPolicyFunc(func(tx *TxSlot, sender [20]byte, isLocal bool) bool {
+		if tx.creation {
+			return true
+		}
+		_, ok := denied[tx.to]
+		return !ok
+	})
+}
+
+// AllowSenders - accepts only transactions of addrs
+func AllowSenders(addrs ...[20]byte) AdmissionPolicy {
+	allowed := addrSet(addrs)
+	return AdmissionPolicyFunc(func(tx *TxSlot, sender [20]byte, isLocal bool) bool {
+		_, ok := allowed[sender]
+		return ok
+	})
+}
+
+// DenyCreation - rejects contract creations. If allowLocal is set, local transactions may create contracts
+func DenyCreation(allowLocal bool) AdmissionPolicy {
+	return AdmissionPolicyFunc(func(tx *TxSlot, sender [20]byte, isLocal bool) bool {
+		return !tx.creation || (allowLocal && isLocal)
+	})
+}
+
+func addrSet(addrs [][20]byte) map[[20]byte]struct{} {
+	set := make(map[[20]byte]struct{}, len(addrs))
+	for _, addr := range addrs {
+		set[addr] = struct{}{}
+	}
+	return set
+}
+
+// SetAdmissionPolicy - replaces TxPoolConfig.Admission at runtime, nil admits all transactions. Pooled transactions
+// which new policy rejects are discarded with PolicyRejected reason
+func (p *TxPool) SetAdmissionPolicy(policy AdmissionPolicy) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.cfg.Admission = policy
+	if policy == nil {
+		return
+	}
+	var rejected []*MetaTx
+	var sender [20]byte
+	for _, mt := range p.byHash {
+		copy(sender[:], p.senderIDs.addr(mt.Tx.senderID))
+		if !policy.Admit(mt.Tx, sender, mt.SubPool&IsLocal != 0) {
+			rejected = append(rejected, mt)
+		}
+	}
+	p.discardLocked(rejected, PolicyRejected)
+	p.gcSenders()
+}
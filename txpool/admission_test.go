@@ -0,0 +1,81 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmissionPolicy(t *testing.T) {
+	require := require.New(t)
+	sanctioned, other := [20]byte{0xaa}, [20]byte{0xbb}
+	cfg := DefaultConfig
+	cfg.Admission = CombinePolicies(DenyRecipients(sanctioned), nil, DenyCreation(true))
+	pool := newTestPool(t, cfg, nil, headerWithBaseFee(0, 1))
+	events, unsubscribe := pool.Subscribe(10)
+	defer unsubscribe()
+
+	var hashes byte
+	newTx := func(sender byte, nonce uint64, to *[20]byte, isLocal bool) (txs TxSlots) {
+		hashes++
+		slot := &TxSlot{nonce: nonce, tip: 1, feeCap: 10, gas: 60000, creation: to == nil}
+		if to != nil {
+			slot.to = *to
+		}
+		slot.idHash[0] = hashes
+		txs.Append(slot, []byte{sender, 19: 0}, isLocal)
+		return txs
+	}
+	add := func(txs TxSlots) DiscardReason {
+		reasons, err := pool.AddLocals(context.Background(), txs)
+		require.NoError(err)
+		return reasons[0]
+	}
+
+	toSanctioned := newTx(1, 0, &sanctioned, true)
+	require.Equal(PolicyRejected, add(toSanctioned))
+	ev := <-events
+	require.Equal(TxRejected, ev.Kind)
+	require.Equal(PolicyRejected, ev.Reason)
+	require.Equal(toSanctioned.txs[0].idHash, ev.Hash)
+	require.Equal([20]byte{1}, ev.Sender)
+	require.False(pool.IdHashKnown(toSanctioned.txs[0].idHash[:]))
+
+	// contract creation is allowed only to local transactions
+	require.Equal(PolicyRejected, add(newTx(2, 0, nil, false)))
+	require.Equal(Success, add(newTx(2, 0, nil, true)))
+	require.Equal(Success, add(newTx(1, 0, &other, true)))
+	require.Equal(Success, add(newTx(1, 1, &other, false)))
+	require.Equal(PoolStatus{Pending: 3}, pool.Status())
+
+	// new policy evicts pooled transactions it rejects: transactions after nonce gap are queued
+	pool.SetAdmissionPolicy(CombinePolicies(AllowSenders([20]byte{1}), AdmissionPolicyFunc(func(tx *TxSlot, sender [20]byte, isLocal bool) bool {
+		return tx.Nonce() != 0
+	})))
+	require.Equal(PoolStatus{Queued: 1}, pool.Status())
+	content, ok := pool.SenderContent([20]byte{1})
+	require.True(ok)
+	require.Equal(1, len(content.Txs))
+	require.Equal(uint64(1), content.Txs[0].Nonce)
+
+	require.Equal(PolicyRejected, add(newTx(2, 0, &other, true)))
+	pool.SetAdmissionPolicy(nil)
+	require.Equal(Success, add(newTx(2, 0, &other, true)))
+}
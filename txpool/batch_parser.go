@@ -0,0 +1,89 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/holiman/uint256"
+	"go.uber.org/atomic"
+)
+
+// BatchParser - parses batches of transactions on several goroutines. Sender recovery is the most expensive part
+// of parsing, and TxParseContext can't be shared, so every worker has its own context
+type BatchParser struct {
+	lock sync.Mutex // batches are parsed one by one, every batch uses all contexts
+	ctxs []*TxParseContext
+}
+
+// NewBatchParser - workers is amount of goroutines parsing one batch. Transactions signed for other chains than
+// chainID are rejected (zero chainID disables the check, see TxParseContext.WithChainID)
+func NewBatchParser(workers int, chainID uint256.Int) *BatchParser {
+	if workers < 1 {
+		workers = 1
+	}
+	b := &BatchParser{ctxs: make([]*TxParseContext, workers)}
+	for i := range b.ctxs {
+		b.ctxs[i] = NewTxParseContext().WithChainID(chainID)
+	}
+	return b
+}
+
+// Parse - parses every transaction of txsRlp (as returned by splitTransactions) and appends parsed ones to
+// txSlots (as remote transactions) in order of txsRlp. Senders of transactions for which known returns true are
+// not recovered, their hashes are returned in knownHashes. known can be nil. Transactions which fail to parse
+// are skipped and their errors are returned in parseErrs
+func (b *BatchParser) Parse(txsRlp [][]byte, known func(idHash []byte) bool, txSlots *TxSlots) (knownHashes Hashes, parseErrs []error) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	slots := make([]*TxSlot, len(txsRlp))
+	senders := make([][20]byte, len(txsRlp))
+	errs := make([]error, len(txsRlp))
+
+	workers := len(b.ctxs)
+	if workers > len(txsRlp) {
+		workers = len(txsRlp)
+	}
+	var next atomic.Int64 // index of the next transaction to parse, plus one
+	var wg sync.WaitGroup
+	for _, ctx := range b.ctxs[:workers] {
+		wg.Add(1)
+		go func(ctx *TxParseContext) {
+			defer wg.Done()
+			ctx.known = known
+			defer func() { ctx.known = nil }()
+			for i := int(next.Inc() - 1); i < len(txsRlp); i = int(next.Inc() - 1) {
+				slots[i], senders[i], _, errs[i] = ctx.ParseTransaction(txsRlp[i], 0)
+			}
+		}(ctx)
+	}
+	wg.Wait()
+
+	for i := range txsRlp {
+		switch {
+		case errors.Is(errs[i], ErrAlreadyKnown):
+			knownHashes = append(knownHashes, slots[i].idHash[:]...)
+		case errs[i] != nil:
+			parseErrs = append(parseErrs, fmt.Errorf("transaction %d: %w", i, errs[i]))
+		default:
+			txSlots.Append(slots[i], senders[i][:], false)
+		}
+	}
+	return knownHashes, parseErrs
+}
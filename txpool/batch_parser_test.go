@@ -0,0 +1,66 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBatchParser(t *testing.T) {
+	require := require.New(t)
+	var txsRlp [][]byte
+	for _, tt := range txParseTests {
+		txsRlp = append(txsRlp, decodeHex(tt.payloadStr))
+	}
+	// broken transaction in the middle of the batch
+	txsRlp = append(txsRlp[:2:2], append([][]byte{decodeHex("c3010203")}, txsRlp[2:]...)...)
+	knownHash := decodeHex(txParseTests[1].idHashStr)
+	known := func(idHash []byte) bool { return bytes.Equal(idHash, knownHash) }
+
+	for _, workers := range []int{0, 1, 3, 64} {
+		var txs TxSlots
+		knownHashes, parseErrs := NewBatchParser(workers, uint256.Int{}).Parse(txsRlp, known, &txs)
+		require.Len(parseErrs, 1)
+		require.Equal(Hashes(knownHash), knownHashes)
+		// results are in order of input
+		require.Equal(len(txParseTests)-1, len(txs.txs))
+		for i, tt := range append(txParseTests[:1:1], txParseTests[2:]...) {
+			require.Equal(decodeHex(tt.payloadStr), txs.txs[i].rlp)
+			require.Equal(decodeHex(tt.idHashStr), txs.txs[i].idHash[:])
+			if tt.senderStr != "" {
+				require.Equal(decodeHex(tt.senderStr), txs.senders[i*20:(i+1)*20])
+			}
+		}
+	}
+
+	// transactions of other chains are rejected
+	var txs TxSlots
+	_, parseErrs := NewBatchParser(2, *uint256.NewInt(1)).Parse(txsRlp, nil, &txs)
+	require.Less(len(txs.txs), len(txParseTests))
+	otherChain := 0
+	for _, err := range parseErrs {
+		if errors.Is(err, ErrInvalidChainID) {
+			otherChain++
+		}
+	}
+	require.Equal(len(parseErrs)-1, otherChain)
+}
@@ -0,0 +1,112 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import "sort"
+
+// BestTxs - iterator over executable transactions of the pending sub-pool, from the best to the worst.
+// Transactions of one sender go in nonce order. It works on a snapshot, so the pool is not modified and
+// may change while iterator is in use. Iterator is not thread-safe
+type BestTxs struct {
+	txs          []bestTx
+	pos          int
+	skipped      map[uint64]struct{} // senderID
+	lastSender   uint64
+	gasRemaining uint64
+}
+
+type bestTx struct {
+	rlp      []byte
+	sender   []byte
+	senderID uint64
+	gas      uint64
+}
+
+// Best - takes snapshot of the pending sub-pool. gasLimit is initial value of GasRemaining (usually gas limit
+// of the block being built)
+func (p *TxPool) Best(gasLimit uint64) *BestTxs {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	sorted := append([]*MetaTx{}, *p.pending.best...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[j].Less(sorted[i]) })
+	sortSendersByNonce(sorted)
+	txs := make([]bestTx, len(sorted))
+	for i, mt := range sorted {
+		txs[i] = bestTx{rlp: mt.Tx.rlp, sender: p.senderIDs.addr(mt.Tx.senderID), senderID: mt.Tx.senderID, gas: mt.Tx.gas}
+	}
+	return &BestTxs{txs: txs, skipped: map[uint64]struct{}{}, gasRemaining: gasLimit}
+}
+
+// sortSendersByNonce - block can't include transactions with nonce gap, so per-sender nonce order is enforced
+// here and not left to MetaTx ordering: transactions of each sender are put in nonce order into the positions
+// which the sender occupies in txs
+func sortSendersByNonce(txs []*MetaTx) {
+	positions := map[uint64][]int{} // senderID -> indices in txs
+	for i, mt := range txs {
+		positions[mt.Tx.senderID] = append(positions[mt.Tx.senderID], i)
+	}
+	var senderTxs []*MetaTx
+	for _, indices := range positions {
+		if len(indices) == 1 {
+			continue
+		}
+		senderTxs = senderTxs[:0]
+		for _, i := range indices {
+			senderTxs = append(senderTxs, txs[i])
+		}
+		sort.Slice(senderTxs, func(i, j int) bool { return senderTxs[i].Tx.nonce < senderTxs[j].Tx.nonce })
+		for k, i := range indices {
+			txs[i] = senderTxs[k]
+		}
+	}
+}
+
+// Next - returns RLP and sender of the next transaction, ok=false when there are no more transactions.
+// Transactions which need more gas than GasRemaining are not returned, and neither are next transactions of
+// their senders - they would have nonce gap. Gas is not subtracted here: caller knows how much gas
+// transaction actually used, and reports it by SetGasRemaining
+func (b *BestTxs) Next() (rlp, sender []byte, ok bool) {
+	for ; b.pos < len(b.txs); b.pos++ {
+		tx := b.txs[b.pos]
+		if _, skip := b.skipped[tx.senderID]; skip {
+			continue
+		}
+		if tx.gas > b.gasRemaining {
+			b.skipped[tx.senderID] = struct{}{}
+			continue
+		}
+		b.pos++
+		b.lastSender = tx.senderID
+		return tx.rlp, tx.sender, true
+	}
+	return nil, nil, false
+}
+
+// SkipSender - skips all next transactions of the sender of the transaction returned last by Next. Used when
+// that transaction failed to execute: next nonces of the sender can't be executed either
+func (b *BestTxs) SkipSender() {
+	if b.lastSender == 0 {
+		return
+	}
+	b.skipped[b.lastSender] = struct{}{}
+}
+
+func (b *BestTxs) GasRemaining() uint64 { return b.gasRemaining }
+
+// SetGasRemaining - updates gas left in the block, transactions which don't fit into it will be skipped
+func (b *BestTxs) SetGasRemaining(gas uint64) { b.gasRemaining = gas }
@@ -0,0 +1,108 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBestTxs(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+
+	var txs TxSlots
+	for i, tx := range []struct {
+		sender          byte
+		nonce, tip, gas uint64
+	}{
+		{sender: 1, nonce: 0, tip: 5, gas: 21000},
+		{sender: 1, nonce: 1, tip: 50, gas: 21000},
+		{sender: 2, nonce: 0, tip: 3, gas: 100000},
+		{sender: 2, nonce: 1, tip: 3, gas: 21000},
+		{sender: 3, nonce: 0, tip: 4, gas: 21000},
+		{sender: 3, nonce: 1, tip: 4, gas: 21000},
+		{sender: 4, nonce: 0, tip: 1, gas: 21000},
+	} {
+		slot := &TxSlot{nonce: tx.nonce, tip: tx.tip, feeCap: 100, gas: tx.gas, rlp: []byte{byte(i)}}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, []byte{tx.sender, 19: 0}, true)
+	}
+	reasons, err := pool.AddLocals(context.Background(), txs)
+	require.NoError(err)
+	for _, reason := range reasons {
+		require.Equal(Success, reason)
+	}
+
+	best := pool.Best(150000)
+	require.Equal(uint64(150000), best.GasRemaining())
+	var order []byte
+	for rlp, sender, ok := best.Next(); ok; rlp, sender, ok = best.Next() {
+		order = append(order, rlp[0])
+		switch sender[0] {
+		case 3:
+			// first transaction of sender 3 failed to execute, its next nonce can't be included
+			best.SkipSender()
+		default:
+			best.SetGasRemaining(best.GasRemaining() - 21000)
+		}
+		if len(order) == 1 {
+			// block got almost full, transaction of sender 2 doesn't fit anymore
+			best.SetGasRemaining(90000)
+		}
+	}
+	require.Equal([]byte{0, 1, 4, 6}, order)
+
+	// iterator doesn't modify the pool
+	require.Equal(7, pool.pending.Len())
+	rlp, _, ok := pool.Best(1_000_000).Next()
+	require.True(ok)
+	require.Equal(byte(0), rlp[0])
+}
+
+func TestBestTxsLocalAndRemote(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+	var txs TxSlots
+	for i, tx := range []struct {
+		sender  byte
+		nonce   uint64
+		isLocal bool
+	}{
+		{sender: 1, nonce: 0},
+		{sender: 1, nonce: 1, isLocal: true},
+		{sender: 1, nonce: 2},
+		{sender: 2, nonce: 0, isLocal: true},
+	} {
+		slot := &TxSlot{nonce: tx.nonce, tip: 1, feeCap: 100, gas: 21000, rlp: []byte{byte(i)}}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, []byte{tx.sender, 19: 0}, tx.isLocal)
+	}
+	reasons, err := pool.AddLocals(context.Background(), txs)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success, Success, Success, Success}, reasons)
+
+	// local transaction of sender 1 doesn't go ahead of its previous remote nonce
+	best := pool.Best(1_000_000)
+	var order []byte
+	for rlp, _, ok := best.Next(); ok; rlp, _, ok = best.Next() {
+		order = append(order, rlp[0])
+	}
+	require.Equal([]byte{3, 0, 1, 2}, order)
+}
@@ -0,0 +1,233 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/log/v3"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// BlockStream connects to TxpoolControl service of Erigon and consumes stream of BlockDiffs (see
+// interfaces/txpool/README.md): applied blocks update state of senders and remove mined transactions,
+// reverted blocks also return their transactions to the pool. BlockDiffs must go without gaps - if parent
+// of the next block doesn't match last seen block, state of all senders is re-requested
+type BlockStream struct {
+	ctx         context.Context
+	client      txpool_proto.TxpoolControlClient
+	pool        *TxPool
+	senderState *RemoteSenderStateProvider // optional, moved to the last seen block - to load state of new senders consistently
+	logger      log.Logger
+
+	parseCtx      *TxParseContext // used to parse transactions of reverted blocks
+	lastBlockHash [32]byte
+	hasLastBlock  bool
+}
+
+func NewBlockStream(ctx context.Context, client txpool_proto.TxpoolControlClient, pool *TxPool, senderState *RemoteSenderStateProvider, logger log.Logger) *BlockStream {
+	s := &BlockStream{
+		ctx:         ctx,
+		client:      client,
+		pool:        pool,
+		senderState: senderState,
+		logger:      logger,
+		parseCtx:    NewTxParseContext(),
+	}
+	// pool restored from the db continues from the block it has seen last
+	if hash, ok := pool.LastSeenBlock(); ok {
+		s.setLastBlock(hash)
+	}
+	return s
+}
+
+// Start subscribes to BlockStream, re-subscribing after errors until ctx is cancelled
+func (s *BlockStream) Start() {
+	go s.loop()
+}
+
+func (s *BlockStream) loop() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+		err := s.subscribe()
+		if err == nil {
+			continue
+		}
+		select {
+		case <-s.ctx.Done():
+			return
+		default:
+		}
+		if st, ok := status.FromError(err); ok && st.Code() == codes.Canceled {
+			return
+		}
+		// Report error and wait more
+		s.logger.Warn("[txpool] block stream", "err", err)
+		time.Sleep(time.Second)
+	}
+}
+
+// subscribe - consumes one BlockStream subscription until it fails. After reconnect the stream continues
+// from the last seen block
+func (s *BlockStream) subscribe() error {
+	streamCtx, cancel := context.WithCancel(s.ctx)
+	defer cancel()
+
+	req := &txpool_proto.BlockStreamRequest{StartWith: &txpool_proto.BlockStreamRequest_Latest{Latest: &emptypb.Empty{}}}
+	if s.hasLastBlock {
+		req.StartWith = &txpool_proto.BlockStreamRequest_BlockHash{BlockHash: gointerfaces.ConvertHashToH256(s.lastBlockHash)}
+	}
+	stream, err := s.client.BlockStream(streamCtx, req, grpc.WaitForReady(true))
+	if err != nil {
+		return err
+	}
+	for {
+		diff, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err = s.handleBlockDiff(diff); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *BlockStream) handleBlockDiff(diff *txpool_proto.BlockDiff) error {
+	// BlockDiff doesn't carry in-protocol minimal base fee, it's configured
+	protocolBaseFee := s.pool.cfg.ProtocolBaseFee
+	switch d := diff.Diff.(type) {
+	case *txpool_proto.BlockDiff_Applied:
+		hash := gointerfaces.ConvertH256ToHash(d.Applied.Hash)
+		parentHash := gointerfaces.ConvertH256ToHash(d.Applied.ParentHash)
+		head := BlockHeader{
+			Height:   d.Applied.BlockHeight,
+			GasUsed:  d.Applied.GasUsed,
+			GasLimit: d.Applied.GasLimit,
+			BaseFee:  d.Applied.BaseFee,
+		}
+		if s.hasLastBlock && parentHash != s.lastBlockHash {
+			return s.resync(hash, head)
+		}
+		return s.advance(hash, func() error {
+			if err := s.pool.OnNewBlock(stateChangesFromAccounts(d.Applied.ChangedAccounts), TxSlots{}, TxSlots{}, head, protocolBaseFee); err != nil {
+				return fmt.Errorf("applying block %x: %w", hash, err)
+			}
+			return nil
+		})
+	case *txpool_proto.BlockDiff_Reverted:
+		revertedHash := gointerfaces.ConvertH256ToHash(d.Reverted.RevertedHash)
+		newHash := gointerfaces.ConvertH256ToHash(d.Reverted.NewHash)
+		head := BlockHeader{
+			Height:   d.Reverted.NewBlockHeight,
+			GasUsed:  d.Reverted.NewGasUsed,
+			GasLimit: d.Reverted.NewGasLimit,
+			BaseFee:  d.Reverted.NewBaseFee,
+		}
+		if s.hasLastBlock && revertedHash != s.lastBlockHash {
+			return s.resync(newHash, head)
+		}
+		var unwindTxs TxSlots
+		for i, rlp := range d.Reverted.RevertedTransactions {
+			// typed transactions may come in canonical encoding, as they are in blocks
+			slot, sender, err := s.parseCtx.ParseSingleTransaction(wrapTypedTx(rlp))
+			if err != nil {
+				s.logger.Warn("[txpool] parsing reverted transaction", "block", fmt.Sprintf("%x", revertedHash), "i", i, "err", err)
+				continue
+			}
+			unwindTxs.Append(slot, sender[:], false)
+		}
+		return s.advance(newHash, func() error {
+			if err := s.pool.OnNewBlock(stateChangesFromAccounts(d.Reverted.RevertedAccounts), unwindTxs, TxSlots{}, head, protocolBaseFee); err != nil {
+				return fmt.Errorf("reverting block %x: %w", revertedHash, err)
+			}
+			return nil
+		})
+	default:
+		return fmt.Errorf("unexpected BlockDiff: %T", diff.Diff)
+	}
+}
+
+// resync - is called on gap in the stream: pool doesn't know which state changes it missed, so state of all
+// senders is re-requested at the new block. Only head of the new block is applied
+func (s *BlockStream) resync(hash [32]byte, head BlockHeader) error {
+	s.logger.Warn("[txpool] gap in block stream, resyncing senders", "last", fmt.Sprintf("%x", s.lastBlockHash), "new", fmt.Sprintf("%x", hash))
+	return s.advance(hash, func() error {
+		if err := s.pool.ResetSenders(); err != nil {
+			return err
+		}
+		if err := s.pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, head, s.pool.cfg.ProtocolBaseFee); err != nil {
+			return fmt.Errorf("applying block %x: %w", hash, err)
+		}
+		return nil
+	})
+}
+
+// advance - moves the stream to block hash, if apply of its changes to the pool succeeds. Otherwise the stream
+// stays at the last applied block, so the failed block is received again after re-subscription. State of senders
+// loaded while apply runs is requested at the new block
+func (s *BlockStream) advance(hash [32]byte, apply func() error) error {
+	prevHash, hadPrev := s.lastBlockHash, s.hasLastBlock
+	if s.senderState != nil {
+		s.senderState.SetBlockHash(hash)
+	}
+	if err := apply(); err != nil {
+		if s.senderState != nil && hadPrev {
+			s.senderState.SetBlockHash(prevHash)
+		}
+		return err
+	}
+	s.setLastBlock(hash)
+	s.pool.SetLastSeenBlock(hash)
+	return nil
+}
+
+func (s *BlockStream) setLastBlock(hash [32]byte) {
+	s.lastBlockHash, s.hasLastBlock = hash, true
+	if s.senderState != nil {
+		s.senderState.SetBlockHash(hash)
+	}
+}
+
+func stateChangesFromAccounts(accounts []*txpool_proto.AccountInfo) map[string]senderInfo {
+	stateChanges := make(map[string]senderInfo, len(accounts))
+	for _, acc := range accounts {
+		addr := gointerfaces.ConvertH160toAddress(acc.Address)
+		var balance uint256.Int
+		if acc.Balance != nil {
+			balance = *gointerfaces.ConvertH256ToUint256Int(acc.Balance)
+		}
+		stateChanges[string(addr[:])] = senderInfo{nonce: acc.Nonce, balance: balance}
+	}
+	return stateChanges
+}
@@ -0,0 +1,142 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBlockStream(t *testing.T) {
+	require := require.New(t)
+	var loads int32
+	// pool knows nothing but what comes with the stream
+	pool, err := New(make(chan Hashes, 10), DefaultConfig, senderStateProviderMock(func(ctx context.Context, addr [20]byte) (uint64, uint256.Int, error) {
+		atomic.AddInt32(&loads, 1)
+		return 0, testBalance, nil
+	}))
+	require.NoError(err)
+	s := NewBlockStream(context.Background(), nil, pool, nil, log.New())
+	account := func(nonce uint64) []*txpool_proto.AccountInfo {
+		return []*txpool_proto.AccountInfo{{
+			Address: gointerfaces.ConvertAddressToH160(toAddr(txParseTests[0].senderStr)),
+			Balance: gointerfaces.ConvertUint256IntToH256(&testBalance),
+			Nonce:   nonce,
+		}}
+	}
+	h0, h1, h2, h3 := [32]byte{0}, [32]byte{1}, [32]byte{2}, [32]byte{3}
+
+	require.NoError(s.handleBlockDiff(&txpool_proto.BlockDiff{Diff: &txpool_proto.BlockDiff_Applied{Applied: &txpool_proto.AppliedBlock{
+		Hash:        gointerfaces.ConvertHashToH256(h1),
+		ParentHash:  gointerfaces.ConvertHashToH256(h0),
+		BlockHeight: 1,
+		GasUsed:     15_000_000,
+		GasLimit:    30_000_000,
+		BaseFee:     8,
+	}}}))
+	require.Equal(DefaultConfig.ProtocolBaseFee, pool.protocolBaseFee.Load())
+	txs := parseTxSlots(t, false, txParseTests[0].payloadStr)
+	idHash := txs.txs[0].idHash
+	require.NoError(pool.OnNewTxs(txs))
+	require.Eventually(func() bool { return inPool(pool, idHash[:]) }, time.Second, time.Millisecond)
+
+	// transaction is mined: BlockDiff has no transactions, used nonce is enough
+	events, unsubscribe := pool.Subscribe(10)
+	defer unsubscribe()
+	require.NoError(s.handleBlockDiff(&txpool_proto.BlockDiff{Diff: &txpool_proto.BlockDiff_Applied{Applied: &txpool_proto.AppliedBlock{
+		Hash:            gointerfaces.ConvertHashToH256(h2),
+		ParentHash:      gointerfaces.ConvertHashToH256(h1),
+		ChangedAccounts: account(1),
+		BlockHeight:     2,
+		GasUsed:         30_000_000,
+		GasLimit:        30_000_000,
+		BaseFee:         8,
+	}}}))
+	require.False(pool.IdHashKnown(idHash[:]))
+	ev := <-events
+	require.Equal(TxMined, ev.Kind)
+	require.Equal(idHash, ev.Hash)
+	unsubscribe()
+	require.Equal(uint64(2), pool.blockHeight.Load())
+	require.Equal(uint64(9), pool.PendingBaseFee())
+
+	// block is reverted - transaction returns to the pool
+	require.NoError(s.handleBlockDiff(&txpool_proto.BlockDiff{Diff: &txpool_proto.BlockDiff_Reverted{Reverted: &txpool_proto.RevertedBlock{
+		RevertedHash:         gointerfaces.ConvertHashToH256(h2),
+		RevertedTransactions: [][]byte{decodeHex(txParseTests[0].payloadStr)},
+		NewHash:              gointerfaces.ConvertHashToH256(h1),
+		RevertedAccounts:     account(0),
+		NewBlockHeight:       1,
+		NewGasUsed:           15_000_000,
+		NewGasLimit:          30_000_000,
+		NewBaseFee:           8,
+	}}}))
+	require.True(pool.IdHashKnown(idHash[:]))
+	require.Equal(uint64(1), pool.blockHeight.Load())
+	require.Equal(uint64(8), pool.PendingBaseFee())
+	require.Equal(h1, s.lastBlockHash)
+	require.Equal(int32(1), atomic.LoadInt32(&loads))
+
+	// gap in the stream - state of senders is requested again, changes of the block are not applied, its
+	// header is
+	require.NoError(s.handleBlockDiff(&txpool_proto.BlockDiff{Diff: &txpool_proto.BlockDiff_Applied{Applied: &txpool_proto.AppliedBlock{
+		Hash:            gointerfaces.ConvertHashToH256(h3),
+		ParentHash:      gointerfaces.ConvertHashToH256(h2),
+		ChangedAccounts: account(1),
+		BlockHeight:     5,
+		GasUsed:         15_000_000,
+		GasLimit:        30_000_000,
+		BaseFee:         100,
+	}}}))
+	require.Equal(h3, s.lastBlockHash)
+	require.Equal(uint64(5), pool.blockHeight.Load())
+	require.Equal(uint64(100), pool.PendingBaseFee())
+	require.Eventually(func() bool { return inPool(pool, idHash[:]) }, time.Second, time.Millisecond)
+	require.Equal(int32(2), atomic.LoadInt32(&loads))
+}
+
+func TestBlockStreamRevertedTypedTx(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(1, 8))
+	s := NewBlockStream(context.Background(), nil, pool, nil, log.New())
+
+	// reverted type-2 transaction in canonical encoding: type byte followed by RLP list, without string wrapper
+	require.NoError(s.handleBlockDiff(&txpool_proto.BlockDiff{Diff: &txpool_proto.BlockDiff_Reverted{Reverted: &txpool_proto.RevertedBlock{
+		RevertedHash:         gointerfaces.ConvertHashToH256([32]byte{2}),
+		RevertedTransactions: [][]byte{decodeHex(txParseTests[2].payloadStr[4:])},
+		NewHash:              gointerfaces.ConvertHashToH256([32]byte{1}),
+		RevertedAccounts: []*txpool_proto.AccountInfo{{
+			Address: gointerfaces.ConvertAddressToH160(toAddr(txParseTests[2].senderStr)),
+			Balance: gointerfaces.ConvertUint256IntToH256(&testBalance),
+		}},
+		NewBlockHeight: 1,
+		NewGasUsed:     15_000_000,
+		NewGasLimit:    30_000_000,
+		NewBaseFee:     8,
+	}}}))
+	hash := toHash(txParseTests[2].idHashStr)
+	require.True(inPool(pool, hash[:]))
+	require.Equal(decodeHex(txParseTests[2].payloadStr), pool.GetRlp(hash[:]))
+}
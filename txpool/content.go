@@ -0,0 +1,131 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"github.com/google/btree"
+	"github.com/holiman/uint256"
+)
+
+// PoolStatus - amount of transactions in every sub-pool, like txpool_status of geth
+type PoolStatus struct {
+	Pending, BaseFee, Queued int
+}
+
+// TxContent - transaction of the pool, as reported by introspection methods
+type TxContent struct {
+	Hash        [32]byte
+	Nonce       uint64
+	Tip, FeeCap uint64
+	Gas         uint64
+	Value       uint256.Int
+	Rlp         []byte
+	SubPool     SubPoolType
+	Marker      SubPoolMarker // see SubPoolMarker.Bits
+	Private     bool          // never sent to peers, see TxSlots.AppendPrivate
+}
+
+// SenderContent - state of the sender known to the pool, and its transactions in nonce order
+type SenderContent struct {
+	Address      [20]byte
+	Nonce        uint64      // state nonce
+	Balance      uint256.Int // state balance
+	PendingNonce uint64      // see TxPool.PendingNonce
+	Txs          []TxContent
+}
+
+func (p *TxPool) Status() PoolStatus {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return PoolStatus{Pending: p.pending.Len(), BaseFee: p.baseFee.Len(), Queued: p.queued.Len()}
+}
+
+// Content - all senders which have transactions in the pool, like txpool_content of geth
+func (p *TxPool) Content() []*SenderContent {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	content := make([]*SenderContent, 0, len(p.senderInfo))
+	for id, sender := range p.senderInfo {
+		if sender.txNonce2Tx.Len() == 0 {
+			continue
+		}
+		content = append(content, p.senderContent(id, sender))
+	}
+	return content
+}
+
+// SenderContent - state and transactions of the sender, ok=false if state of the sender is not known to the pool
+func (p *TxPool) SenderContent(addr [20]byte) (content *SenderContent, ok bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	id, ok := p.senderIDs.id(string(addr[:]))
+	if !ok {
+		return nil, false
+	}
+	sender, ok := p.senderInfo[id]
+	if !ok {
+		return nil, false
+	}
+	return p.senderContent(id, sender), true
+}
+
+// PendingNonce - nonce of the next transaction of the sender: state nonce, increased by every pooled transaction
+// which continues it without gaps. ok=false if state of the sender is not known to the pool - then the caller
+// must use nonce from the state, same way as eth_getTransactionCount("pending") does
+func (p *TxPool) PendingNonce(addr [20]byte) (nonce uint64, ok bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	id, ok := p.senderIDs.id(string(addr[:]))
+	if !ok {
+		return 0, false
+	}
+	sender, ok := p.senderInfo[id]
+	if !ok {
+		return 0, false
+	}
+	return pendingNonce(sender), true
+}
+
+func pendingNonce(sender *senderInfo) uint64 {
+	nonce := sender.nonce
+	sender.txNonce2Tx.Ascend(func(i btree.Item) bool {
+		if i.(*nonce2TxItem).MetaTx.Tx.nonce != nonce {
+			return false
+		}
+		nonce++
+		return true
+	})
+	return nonce
+}
+
+// senderContent - must be called under lock
+func (p *TxPool) senderContent(id uint64, sender *senderInfo) *SenderContent {
+	content := &SenderContent{Nonce: sender.nonce, Balance: sender.balance, PendingNonce: pendingNonce(sender)}
+	copy(content.Address[:], p.senderIDs.addr(id))
+	sender.txNonce2Tx.Ascend(func(i btree.Item) bool {
+		mt := i.(*nonce2TxItem).MetaTx
+		tx := newTxContent(mt.Tx)
+		tx.SubPool, tx.Marker, tx.Private = mt.currentSubPool, mt.SubPool, mt.private
+		content.Txs = append(content.Txs, tx)
+		return true
+	})
+	return content
+}
+
+func newTxContent(slot *TxSlot) TxContent {
+	return TxContent{Hash: slot.idHash, Nonce: slot.nonce, Tip: slot.tip, FeeCap: slot.feeCap, Gas: slot.gas, Value: slot.value, Rlp: slot.rlp}
+}
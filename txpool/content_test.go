@@ -0,0 +1,65 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolContent(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+	addr := [20]byte{1}
+	var txs TxSlots
+	for _, nonce := range []uint64{0, 1, 3} {
+		slot := &TxSlot{nonce: nonce, tip: 1, feeCap: 100, gas: 21000, rlp: []byte{byte(nonce)}}
+		slot.idHash[0] = byte(nonce)
+		txs.Append(slot, addr[:], false)
+	}
+	reasons, err := pool.AddLocals(context.Background(), txs)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success, Success, Success}, reasons)
+
+	require.Equal(PoolStatus{Pending: 2, Queued: 1}, pool.Status())
+
+	// nonce 3 is after the gap - it doesn't move pending nonce
+	nonce, ok := pool.PendingNonce(addr)
+	require.True(ok)
+	require.Equal(uint64(2), nonce)
+	_, ok = pool.PendingNonce([20]byte{2})
+	require.False(ok)
+
+	content, ok := pool.SenderContent(addr)
+	require.True(ok)
+	require.Equal(addr, content.Address)
+	require.Equal(uint64(0), content.Nonce)
+	require.Equal(testBalance, content.Balance)
+	require.Equal(uint64(2), content.PendingNonce)
+	require.Len(content.Txs, 3)
+	require.Equal(PendingSubPool, content.Txs[0].SubPool)
+	require.Equal([]byte{1}, content.Txs[1].Rlp)
+	require.Equal(QueuedSubPool, content.Txs[2].SubPool)
+	require.Equal(uint64(3), content.Txs[2].Nonce)
+	require.Equal(SubPoolMarkerBits{EnoughFeeCapProtocol: true, EnoughBalance: true, EnoughFeeCapBlock: true}, content.Txs[2].Marker.Bits())
+
+	all := pool.Content()
+	require.Len(all, 1)
+	require.Equal(content, all[0])
+}
@@ -0,0 +1,147 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"sync"
+)
+
+// EventKind - kind of change of transaction in the pool, see TxPool.Subscribe
+type EventKind uint8
+
+const (
+	TxAdded      EventKind = 1 // new transaction is added to sub-pool To
+	TxReplaced   EventKind = 2 // transaction is replaced by one with same sender and nonce, but higher fees
+	TxPromoted   EventKind = 3 // transaction moved from sub-pool From to better sub-pool To
+	TxDemoted    EventKind = 4 // transaction moved from sub-pool From to worse sub-pool To
+	TxMined      EventKind = 5 // transaction (or only its nonce, if transactions of the block are unknown) is included into a block
+	TxDiscarded  EventKind = 6 // transaction is removed from the pool because of Reason
+	TxReinjected EventKind = 7 // transaction of reverted block is returned to sub-pool To
+	TxRejected   EventKind = 8 // new transaction is not admitted into the pool because of Reason, AlreadyKnown is not reported
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case TxAdded:
+		return "added"
+	case TxReplaced:
+		return "replaced"
+	case TxPromoted:
+		return "promoted"
+	case TxDemoted:
+		return "demoted"
+	case TxMined:
+		return "mined"
+	case TxDiscarded:
+		return "discarded"
+	case TxReinjected:
+		return "reinjected"
+	case TxRejected:
+		return "rejected"
+	default:
+		return "unknown"
+	}
+}
+
+// Event - change of one transaction in the pool
+type Event struct {
+	Kind   EventKind
+	Hash   [32]byte
+	Sender [20]byte
+	// From - sub-pool transaction left (TxPromoted, TxDemoted), To - sub-pool transaction is in after the change
+	// (TxAdded, TxPromoted, TxDemoted, TxReinjected). Zero if not applicable
+	From, To SubPoolType
+	Reason   DiscardReason // why transaction left the pool (Mined, Replaced, FeeTooLow, NonceTooLow, ...) or wasn't admitted
+}
+
+// eventFunc - receives changes of transactions from functions which modify the pool. from is sub-pool which
+// transaction left, mt.currentSubPool is sub-pool it's in after the change
+type eventFunc func(mt *MetaTx, kind EventKind, from SubPoolType, reason DiscardReason)
+
+func noEvents(*MetaTx, EventKind, SubPoolType, DiscardReason) {}
+
+// movedEvent - reports move of transaction between sub-pools as promotion or demotion: sub-pools are numbered
+// from the best one
+func movedEvent(emit eventFunc) func(mt *MetaTx, from SubPoolType) {
+	return func(mt *MetaTx, from SubPoolType) {
+		kind := TxPromoted
+		if mt.currentSubPool > from {
+			kind = TxDemoted
+		}
+		emit(mt, kind, from, NotSet)
+	}
+}
+
+// subscribers - channels of TxPool.Subscribe. Pool never waits for subscribers: events which don't fit into
+// channel of slow subscriber are dropped
+type subscribers struct {
+	lock  sync.RWMutex
+	chans map[chan Event]struct{}
+}
+
+func newSubscribers() *subscribers {
+	return &subscribers{chans: map[chan Event]struct{}{}}
+}
+
+func (s *subscribers) subscribe(size int) (<-chan Event, func()) {
+	ch := make(chan Event, size)
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.chans[ch] = struct{}{}
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			delete(s.chans, ch)
+			close(ch)
+		})
+	}
+}
+
+func (s *subscribers) empty() bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return len(s.chans) == 0
+}
+
+func (s *subscribers) send(ev Event) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for ch := range s.chans {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// Subscribe - returns channel of changes of transactions in the pool, buffered by size events. Pool doesn't
+// wait for subscribers: if channel is full, event is dropped. Returned func unsubscribes and closes the channel
+func (p *TxPool) Subscribe(size int) (<-chan Event, func()) {
+	return p.subscribers.subscribe(size)
+}
+
+// onEvent - eventFunc of the pool, sends Event to subscribers. Must be called under lock
+func (p *TxPool) onEvent(mt *MetaTx, kind EventKind, from SubPoolType, reason DiscardReason) {
+	if p.subscribers.empty() {
+		return
+	}
+	ev := Event{Kind: kind, Hash: mt.Tx.idHash, From: from, To: mt.currentSubPool, Reason: reason}
+	copy(ev.Sender[:], p.senderIDs.addr(mt.Tx.senderID))
+	p.subscribers.send(ev)
+}
@@ -0,0 +1,131 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/holiman/uint256"
+)
+
+// EIP-1559 parameters
+const (
+	InitialBaseFee           uint64 = 1_000_000_000 // base fee of the first London block
+	baseFeeChangeDenominator uint64 = 8             // bounds the amount the base fee can change between blocks
+	elasticityMultiplier     uint64 = 2             // bounds the maximum gas limit an EIP-1559 block may have
+)
+
+// BlockHeader - fields of the head block which the pool needs: base fee of the pending block is derived from them
+type BlockHeader struct {
+	Height   uint64
+	GasUsed  uint64
+	GasLimit uint64
+	BaseFee  uint64 // zero before London
+}
+
+// PendingBaseFee - base fee of the block following parent, by EIP-1559. Zero if the block is before London
+func PendingBaseFee(rules ChainRules, parent BlockHeader) uint64 {
+	if !rules.IsLondon(parent.Height + 1) {
+		return 0
+	}
+	if !rules.IsLondon(parent.Height) {
+		return InitialBaseFee
+	}
+	gasTarget := parent.GasLimit / elasticityMultiplier
+	if gasTarget == 0 || parent.GasUsed == gasTarget {
+		return parent.BaseFee
+	}
+	// product of base fee and gas delta may not fit into uint64
+	var delta uint256.Int
+	if parent.GasUsed > gasTarget {
+		delta.Mul(uint256.NewInt(parent.BaseFee), uint256.NewInt(parent.GasUsed-gasTarget))
+		delta.Div(&delta, uint256.NewInt(gasTarget))
+		delta.Div(&delta, uint256.NewInt(baseFeeChangeDenominator))
+		if delta.IsZero() {
+			delta.SetOne()
+		}
+		if !delta.IsUint64() || parent.BaseFee+delta.Uint64() < parent.BaseFee {
+			return math.MaxUint64
+		}
+		return parent.BaseFee + delta.Uint64()
+	}
+	delta.Mul(uint256.NewInt(parent.BaseFee), uint256.NewInt(gasTarget-parent.GasUsed))
+	delta.Div(&delta, uint256.NewInt(gasTarget))
+	delta.Div(&delta, uint256.NewInt(baseFeeChangeDenominator))
+	return parent.BaseFee - delta.Uint64() // delta is at most 1/8 of base fee
+}
+
+// PendingBaseFee - base fee of the pending block, zero before London
+func (p *TxPool) PendingBaseFee() uint64 { return p.blockBaseFee.Load() }
+
+// TipPercentiles - estimate for gas price suggestions, in the style of rewards of eth_feeHistory: tips which
+// pending transactions give to block producer at base fee of the pending block, at given percentiles (ascending,
+// from 0 to 100). Tips are weighted by gas limit of transactions. Returns nil if there are no pending transactions
+func (p *TxPool) TipPercentiles(percentiles []float64) ([]uint64, error) {
+	for i, pct := range percentiles {
+		if pct < 0 || pct > 100 {
+			return nil, fmt.Errorf("invalid percentile: %f", pct)
+		}
+		if i > 0 && pct < percentiles[i-1] {
+			return nil, fmt.Errorf("invalid percentile: #%d:%f > #%d:%f", i-1, percentiles[i-1], i, pct)
+		}
+	}
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return tipPercentiles(*p.pending.best, p.blockBaseFee.Load(), percentiles), nil
+}
+
+type gasAndTip struct{ gas, tip uint64 }
+
+func tipPercentiles(txs []*MetaTx, baseFee uint64, percentiles []float64) []uint64 {
+	if len(txs) == 0 {
+		return nil
+	}
+	tips := make([]gasAndTip, len(txs))
+	var sumGas float64
+	for i, mt := range txs {
+		tips[i] = gasAndTip{gas: mt.Tx.gas, tip: effectiveTip(mt.Tx.tip, mt.Tx.feeCap, baseFee)}
+		sumGas += float64(mt.Tx.gas)
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i].tip < tips[j].tip })
+
+	result := make([]uint64, len(percentiles))
+	var i int
+	cumulativeGas := float64(tips[0].gas)
+	for j, pct := range percentiles {
+		threshold := sumGas * pct / 100
+		for cumulativeGas < threshold && i < len(tips)-1 {
+			i++
+			cumulativeGas += float64(tips[i].gas)
+		}
+		result[j] = tips[i].tip
+	}
+	return result
+}
+
+// effectiveTip - what block producer gets per gas: min(tip, feeCap - baseFee)
+func effectiveTip(tip, feeCap, baseFee uint64) uint64 {
+	if feeCap < baseFee {
+		return 0
+	}
+	if feeCap-baseFee < tip {
+		return feeCap - baseFee
+	}
+	return tip
+}
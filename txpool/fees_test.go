@@ -0,0 +1,87 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// headerWithBaseFee - London block which used exactly its gas target, so the next block has the same base fee
+func headerWithBaseFee(height, baseFee uint64) BlockHeader {
+	return BlockHeader{Height: height, GasUsed: 15_000_000, GasLimit: 30_000_000, BaseFee: baseFee}
+}
+
+func TestPendingBaseFee(t *testing.T) {
+	rules := ChainRules{LondonBlock: 5}
+	cases := []struct {
+		name     string
+		parent   BlockHeader
+		expected uint64
+	}{
+		{"before London", BlockHeader{Height: 3, GasUsed: 30_000_000, GasLimit: 30_000_000}, 0},
+		{"first London block", BlockHeader{Height: 4, GasUsed: 30_000_000, GasLimit: 30_000_000}, InitialBaseFee},
+		{"gas target", BlockHeader{Height: 10, GasUsed: 15_000_000, GasLimit: 30_000_000, BaseFee: 1_000_000_000}, 1_000_000_000},
+		{"full block", BlockHeader{Height: 10, GasUsed: 30_000_000, GasLimit: 30_000_000, BaseFee: 1_000_000_000}, 1_125_000_000},
+		{"empty block", BlockHeader{Height: 10, GasUsed: 0, GasLimit: 30_000_000, BaseFee: 1_000_000_000}, 875_000_000},
+		{"above target", BlockHeader{Height: 10, GasUsed: 20_000_000, GasLimit: 30_000_000, BaseFee: 1_000_000_000}, 1_041_666_666},
+		{"minimal increase", BlockHeader{Height: 10, GasUsed: 30_000_000, GasLimit: 30_000_000, BaseFee: 7}, 8},
+		{"no gas limit", BlockHeader{Height: 10, BaseFee: 7}, 7},
+		{"overflow", BlockHeader{Height: 10, GasUsed: 30_000_000, GasLimit: 30_000_000, BaseFee: math.MaxUint64 - 1}, math.MaxUint64},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			require.Equal(t, c.expected, PendingBaseFee(rules, c.parent))
+		})
+	}
+}
+
+func TestTipPercentiles(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+	require.Equal(uint64(10), pool.PendingBaseFee())
+	tips, err := pool.TipPercentiles([]float64{50})
+	require.NoError(err)
+	require.Nil(tips)
+
+	var txs TxSlots
+	for i, tx := range []struct{ tip, feeCap, gas uint64 }{
+		{tip: 100, feeCap: 1000, gas: 126_000}, // 60% of gas
+		{tip: 5, feeCap: 100, gas: 21_000},     // 10% of gas
+		{tip: 20, feeCap: 25, gas: 63_000},     // 30% of gas, effective tip is 15
+	} {
+		slot := &TxSlot{tip: tx.tip, feeCap: tx.feeCap, gas: tx.gas}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, []byte{byte(i + 1), 19: 0}, true)
+	}
+	reasons, err := pool.AddLocals(context.Background(), txs)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success, Success, Success}, reasons)
+	require.Equal(3, pool.Status().Pending)
+
+	tips, err = pool.TipPercentiles([]float64{0, 10, 25, 40, 50, 100})
+	require.NoError(err)
+	require.Equal([]uint64{5, 5, 15, 15, 100, 100}, tips)
+
+	_, err = pool.TipPercentiles([]float64{101})
+	require.Error(err)
+	_, err = pool.TipPercentiles([]float64{50, 10})
+	require.Error(err)
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"runtime"
 	"sync"
 	"time"
 
@@ -42,31 +43,45 @@ type Fetch struct {
 	sentryClients []sentry.SentryClient // sentry clients that will be used for accessing the network
 	statusData    *sentry.StatusData    // Status data used for "handshaking" with sentries
 	pool          Pool                  // Transaction pool implementation
+	peers         *Peers                // connected peers and hashes they know, shared with Send
 	wg            *sync.WaitGroup       // used for synchronisation in the tests (nil when not in tests)
 	logger        log.Logger
+
+	txsParser *BatchParser // used to parse TRANSACTIONS and POOLED_TRANSACTIONS messages
+	requests  *pooledTxsRequests
+	scores    *peerScores // misbehaviour of peers, misbehaving peers are kicked
 }
 
+// Timings - periods of BroadcastLoop
 type Timings struct {
-	propagateAllNewTxsEvery         time.Duration
-	syncToNewPeersEvery             time.Duration
-	broadcastLocalTransactionsEvery time.Duration
+	SyncToNewPeersEvery time.Duration // recently connected peers receive hashes of all transactions
+	// BroadcastLocalTransactionsEvery - local transactions in pending and baseFee sub-pools are re-announced,
+	// every transaction waits twice longer after each re-announcement (up to maxLocalRebroadcastInterval)
+	BroadcastLocalTransactionsEvery time.Duration
+	CommitEvery                     time.Duration // pool is flushed to db
+	EvictExpiredEvery               time.Duration // see TxPoolConfig.QueuedLifetime, zero - eviction is disabled
 }
 
 var DefaultTimings = Timings{
-	propagateAllNewTxsEvery:         5 * time.Second,
-	broadcastLocalTransactionsEvery: 2 * time.Minute,
-	syncToNewPeersEvery:             2 * time.Minute,
+	BroadcastLocalTransactionsEvery: 2 * time.Minute,
+	SyncToNewPeersEvery:             2 * time.Minute,
+	CommitEvery:                     15 * time.Second,
+	EvictExpiredEvery:               time.Minute, // same as geth
 }
 
 // NewFetch creates a new fetch object that will work with given sentry clients. Since the
 // SentryClient here is an interface, it is suitable for mocking in tests (mock will need
 // to implement all the functions of the SentryClient interface).
+// Transactions received from peers, which are signed for other chain than chainID, are dropped.
+// Forks of chain are announced in eth handshake, pool is expected to be created with the same ChainConfig.
 func NewFetch(ctx context.Context,
 	sentryClients []sentry.SentryClient,
 	genesisHash [32]byte,
 	networkId uint64,
-	forks []uint64,
+	chain ChainConfig,
+	chainID uint256.Int,
 	pool Pool,
+	peers *Peers,
 	logger log.Logger,
 ) *Fetch {
 	statusData := &sentry.StatusData{
@@ -76,7 +91,7 @@ func NewFetch(ctx context.Context,
 		MaxBlock:        0,
 		ForkData: &sentry.Forks{
 			Genesis: gointerfaces.ConvertHashToH256(genesisHash),
-			Forks:   forks,
+			Forks:   chain.Forks,
 		},
 	}
 	return &Fetch{
@@ -84,7 +99,11 @@ func NewFetch(ctx context.Context,
 		sentryClients: sentryClients,
 		statusData:    statusData,
 		pool:          pool,
+		peers:         peers,
 		logger:        logger,
+		txsParser:     NewBatchParser(runtime.NumCPU(), chainID),
+		requests:      newPooledTxsRequests(),
+		scores:        newPeerScores(),
 	}
 }
 
@@ -102,6 +121,82 @@ func (f *Fetch) Start() {
 			f.receivePeerLoop(f.sentryClients[i])
 		}(i)
 	}
+	go f.expireRequestsLoop()
+	go f.decayScoresLoop()
+}
+
+// PeerScores - misbehaviour scores of peers (hex encoded peer id => score), for diagnostics. Peers without
+// misbehaviour are not included
+func (f *Fetch) PeerScores() map[string]int {
+	return f.scores.snapshot()
+}
+
+func (f *Fetch) decayScoresLoop() {
+	ticker := time.NewTicker(scoreDecayEvery)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case now := <-ticker.C:
+			f.scores.decay(now)
+		}
+	}
+}
+
+// misbehaved - increases misbehaviour score of peer, peer which reached kickScore is disconnected
+func (f *Fetch) misbehaved(peer PeerID, sentryClient sentry.SentryClient, score int, reason string) {
+	if !f.scores.add(peer, score) {
+		return
+	}
+	f.logger.Debug("[txpool] kicking misbehaving peer", "peer", fmt.Sprintf("%x", gointerfaces.ConvertH512ToBytes(peer)), "reason", reason)
+	f.peers.Remove(peer)
+	if _, err := sentryClient.PenalizePeer(f.ctx, &sentry.PenalizePeerRequest{PeerId: peer, Penalty: sentry.PenaltyKind_Kick}, &grpc.EmptyCallOption{}); err != nil {
+		f.logger.Warn("[txpool] penalize peer", "err", err)
+	}
+}
+
+// expireRequestsLoop - re-requests transactions, which peers didn't deliver in time, from other peers
+func (f *Fetch) expireRequestsLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-f.ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := f.sendRequests(f.requests.expire(now)); err != nil {
+				f.logger.Warn("[txpool] re-requesting pooled transactions", "err", err)
+			}
+		}
+	}
+}
+
+// sendRequests - sends GET_POOLED_TRANSACTIONS requests. Failed request is not retried here: it will expire
+// and its hashes will be requested from other peers
+func (f *Fetch) sendRequests(reqs []*pooledTxsRequest) error {
+	var firstErr error
+	for _, req := range reqs {
+		var encodedRequest []byte
+		var messageId sentry.MessageId
+		if req.to.eth66 {
+			var err error
+			if encodedRequest, err = EncodeGetPooledTransactions66(req.hashes, req.id, nil); err != nil {
+				return err
+			}
+			messageId = sentry.MessageId_GET_POOLED_TRANSACTIONS_66
+		} else {
+			encodedRequest = EncodeHashes(req.hashes, nil)
+			messageId = sentry.MessageId_GET_POOLED_TRANSACTIONS_65
+		}
+		if _, err := req.to.sentry.SendMessageById(f.ctx, &sentry.SendMessageByIdRequest{
+			Data:   &sentry.OutboundMessageData{Id: messageId, Data: encodedRequest},
+			PeerId: req.to.peer,
+		}, &grpc.EmptyCallOption{}); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
 }
 
 func (f *Fetch) receiveMessageLoop(sentryClient sentry.SentryClient) {
@@ -182,44 +277,42 @@ func (f *Fetch) receiveMessageLoop(sentryClient sentry.SentryClient) {
 }
 
 func (f *Fetch) handleInboundMessage(req *sentry.InboundMessage, sentryClient sentry.SentryClient) error {
+	// peers connected before the pool started are learned from their messages. Messages which kicked peer
+	// sent before disconnect don't make it connected again
+	if !f.scores.isKicked(req.PeerId) {
+		f.addPeer(req.PeerId, sentryClient)
+	}
 	switch req.Id {
 	case sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_65:
 		hashCount, pos, err := ParseHashesCount(req.Data, 0)
 		if err != nil {
+			f.misbehaved(req.PeerId, sentryClient, invalidMessageScore, "invalid NewPooledTransactionHashes")
 			return fmt.Errorf("parsing NewPooledTransactionHashes: %w", err)
 		}
 		var hashbuf [32]byte
-		var unknownHashes Hashes
+		var announced, unknownHashes Hashes
 		for i := 0; i < hashCount; i++ {
 			_, pos, err = ParseHash(req.Data, pos, hashbuf[:0])
 			if err != nil {
+				f.misbehaved(req.PeerId, sentryClient, invalidMessageScore, "invalid NewPooledTransactionHashes")
 				return fmt.Errorf("parsing NewPooledTransactionHashes: %w", err)
 			}
+			announced = append(announced, hashbuf[:]...)
 			if !f.pool.IdHashKnown(hashbuf[:]) {
 				unknownHashes = append(unknownHashes, hashbuf[:]...)
 			}
 		}
+		f.peers.MarkKnown(req.PeerId, announced)
 		if len(unknownHashes) > 0 {
-			var encodedRequest []byte
-			var messageId sentry.MessageId
-			if req.Id == sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66 {
-				if encodedRequest, err = EncodeGetPooledTransactions66(unknownHashes, uint64(1), nil); err != nil {
-					return err
-				}
-				messageId = sentry.MessageId_GET_POOLED_TRANSACTIONS_66
-			} else {
-				encodedRequest = EncodeHashes(unknownHashes, nil)
-				messageId = sentry.MessageId_GET_POOLED_TRANSACTIONS_65
-			}
-			if _, err = sentryClient.SendMessageById(f.ctx, &sentry.SendMessageByIdRequest{
-				Data:   &sentry.OutboundMessageData{Id: messageId, Data: encodedRequest},
-				PeerId: req.PeerId,
-			}, &grpc.EmptyCallOption{}); err != nil {
-				return err
-			}
+			from := announcer{peer: req.PeerId, sentry: sentryClient, eth66: req.Id == sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66}
+			return f.sendRequests(f.requests.announce(from, unknownHashes, time.Now()))
 		}
 	case sentry.MessageId_GET_POOLED_TRANSACTIONS_66, sentry.MessageId_GET_POOLED_TRANSACTIONS_65:
 		//TODO: handleInboundMessage is single-threaded - means it can accept as argument couple buffers (or analog of txParseContext). Protobuf encoding will copy data anyway, but DirectClient doesn't
+		if !f.scores.request(req.PeerId, time.Now()) {
+			f.misbehaved(req.PeerId, sentryClient, requestFloodScore, "too many GetPooledTransactions")
+			return nil
+		}
 		var encodedRequest []byte
 		messageId := sentry.MessageId_POOLED_TRANSACTIONS_66
 		if req.Id == sentry.MessageId_GET_POOLED_TRANSACTIONS_65 {
@@ -228,33 +321,17 @@ func (f *Fetch) handleInboundMessage(req *sentry.InboundMessage, sentryClient se
 		if req.Id == sentry.MessageId_GET_POOLED_TRANSACTIONS_66 {
 			requestID, hashes, _, err := ParseGetPooledTransactions66(req.Data, 0, nil)
 			if err != nil {
+				f.misbehaved(req.PeerId, sentryClient, invalidMessageScore, "invalid GetPooledTransactions")
 				return err
 			}
-			_ = requestID
-			var txs [][]byte
-			for i := 0; i < len(hashes); i += 32 {
-				txn := f.pool.GetRlp(hashes[i : i+32])
-				if txn == nil {
-					continue
-				}
-				txs = append(txs, txn)
-			}
-
-			encodedRequest = EncodePooledTransactions66(txs, requestID, nil)
+			encodedRequest = EncodePooledTransactions66(f.pooledTxs(req.PeerId, sentryClient, hashes), requestID, nil)
 		} else {
 			hashes, _, err := ParseGetPooledTransactions65(req.Data, 0, nil)
 			if err != nil {
+				f.misbehaved(req.PeerId, sentryClient, invalidMessageScore, "invalid GetPooledTransactions")
 				return err
 			}
-			var txs [][]byte
-			for i := 0; i < len(hashes); i += 32 {
-				txn := f.pool.GetRlp(hashes[i : i+32])
-				if txn == nil {
-					continue
-				}
-				txs = append(txs, txn)
-			}
-			encodedRequest = EncodePooledTransactions65(txs, nil)
+			encodedRequest = EncodePooledTransactions65(f.pooledTxs(req.PeerId, sentryClient, hashes), nil)
 		}
 		if _, err := sentryClient.SendMessageById(f.ctx, &sentry.SendMessageByIdRequest{
 			Data:   &sentry.OutboundMessageData{Id: messageId, Data: encodedRequest},
@@ -262,11 +339,86 @@ func (f *Fetch) handleInboundMessage(req *sentry.InboundMessage, sentryClient se
 		}, &grpc.EmptyCallOption{}); err != nil {
 			return err
 		}
+	case sentry.MessageId_POOLED_TRANSACTIONS_66, sentry.MessageId_POOLED_TRANSACTIONS_65, sentry.MessageId_TRANSACTIONS_66, sentry.MessageId_TRANSACTIONS_65:
+		txs, known, requestID, err := f.parseTransactions(req, sentryClient)
+		if err != nil {
+			f.misbehaved(req.PeerId, sentryClient, invalidMessageScore, "invalid "+req.Id.String())
+			return err
+		}
+		hashes := make(Hashes, 0, len(known)+32*len(txs.txs))
+		hashes = append(hashes, known...)
+		for _, tx := range txs.txs {
+			hashes = append(hashes, tx.idHash[:]...)
+		}
+		f.peers.MarkKnown(req.PeerId, hashes)
+		switch req.Id {
+		case sentry.MessageId_POOLED_TRANSACTIONS_66, sentry.MessageId_POOLED_TRANSACTIONS_65:
+			var id *uint64
+			if req.Id == sentry.MessageId_POOLED_TRANSACTIONS_66 {
+				id = &requestID
+			}
+			retry, ok := f.requests.delivered(req.PeerId, id, hashes, time.Now())
+			if !ok {
+				f.misbehaved(req.PeerId, sentryClient, unsolicitedTxsScore, "unsolicited pooled transactions")
+			}
+			if err = f.sendRequests(retry); err != nil {
+				f.logger.Warn("[txpool] re-requesting pooled transactions", "err", err)
+			}
+		default:
+			f.requests.forget(hashes)
+		}
+		if len(txs.txs) == 0 {
+			return nil
+		}
+		if err := f.pool.OnNewTxs(txs); err != nil {
+			return fmt.Errorf("adding %s to the pool: %w", req.Id, err)
+		}
 	}
 
 	return nil
 }
 
+// parseTransactions decodes payload of TRANSACTIONS and POOLED_TRANSACTIONS messages (with request id for
+// POOLED_TRANSACTIONS_66). Senders of transactions known to the pool are not recovered, only their hashes are
+// returned. Transactions which can't be parsed are skipped, peer which sent them is penalised
+func (f *Fetch) parseTransactions(req *sentry.InboundMessage, sentryClient sentry.SentryClient) (txs TxSlots, known Hashes, requestID uint64, err error) {
+	pos := 0
+	if req.Id == sentry.MessageId_POOLED_TRANSACTIONS_66 {
+		if requestID, pos, err = parseRequestID66(req.Data, 0); err != nil {
+			return txs, nil, 0, fmt.Errorf("parsing %s: %w", req.Id, err)
+		}
+	}
+	txsRlp, _, err := splitTransactions(req.Data, pos)
+	if err != nil {
+		return txs, nil, 0, fmt.Errorf("parsing %s: %w", req.Id, err)
+	}
+	known, parseErrs := f.txsParser.Parse(txsRlp, f.pool.IdHashKnown, &txs)
+	for _, parseErr := range parseErrs {
+		f.logger.Debug("skipping invalid transaction", "msg", req.Id, "err", parseErr)
+	}
+	if len(parseErrs) > 0 {
+		f.misbehaved(req.PeerId, sentryClient, len(parseErrs)*invalidTxScore, "invalid transactions")
+	}
+	return txs, known, requestID, nil
+}
+
+// pooledTxs - RLP of requested transactions known to the pool. Only first maxPooledTxsRequestHashes are served,
+// bigger request is misbehaviour
+func (f *Fetch) pooledTxs(peer PeerID, sentryClient sentry.SentryClient, hashes Hashes) (txs [][]byte) {
+	if hashes.Len() > maxPooledTxsRequestHashes {
+		f.misbehaved(peer, sentryClient, oversizedRequestScore, "oversized GetPooledTransactions")
+		hashes = hashes[:maxPooledTxsRequestHashes*32]
+	}
+	for i := 0; i < len(hashes); i += 32 {
+		txn := f.pool.GetRlp(hashes[i : i+32])
+		if txn == nil {
+			continue
+		}
+		txs = append(txs, txn)
+	}
+	return txs
+}
+
 func (f *Fetch) receivePeerLoop(sentryClient sentry.SentryClient) {
 	logger := f.logger
 	for {
@@ -325,7 +477,7 @@ func (f *Fetch) receivePeerLoop(sentryClient sentry.SentryClient) {
 			if req == nil {
 				return
 			}
-			if err = f.handleNewPeer(req); err != nil {
+			if err = f.handleNewPeer(req, sentryClient); err != nil {
 				logger.Warn("Handling new peer", "err", err)
 			}
 			if f.wg != nil {
@@ -335,14 +487,25 @@ func (f *Fetch) receivePeerLoop(sentryClient sentry.SentryClient) {
 	}
 }
 
-func (f *Fetch) handleNewPeer(req *sentry.PeersReply) error {
+func (f *Fetch) handleNewPeer(req *sentry.PeersReply, sentryClient sentry.SentryClient) error {
 	if req == nil {
 		return nil
 	}
 	switch req.Event {
 	case sentry.PeersReply_Connect:
-		f.pool.NotifyNewPeer(req.PeerId)
+		f.addPeer(req.PeerId, sentryClient)
+		f.pool.OnNewPeer(req.PeerId)
+	case sentry.PeersReply_Disconnect:
+		f.peers.Remove(req.PeerId)
+		f.scores.remove(req.PeerId)
 	}
 
 	return nil
 }
+
+// addPeer - only sentries which report their protocol version can be used to send messages to the peer
+func (f *Fetch) addPeer(peer PeerID, sentryClient sentry.SentryClient) {
+	if c, ok := sentryClient.(SentryClient); ok {
+		f.peers.Add(peer, c)
+	}
+}
@@ -0,0 +1,220 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+)
+
+const (
+	// pooledTxsRequestTimeout - if peer doesn't deliver requested transactions in time, they are requested from
+	// the next peer which announced them
+	pooledTxsRequestTimeout = 5 * time.Second
+	// maxPooledTxsRequestHashes - limit of hashes in one GET_POOLED_TRANSACTIONS request, same as in geth
+	maxPooledTxsRequestHashes = 256
+)
+
+// announcer - peer which announced transaction hashes, and sentry it's connected to
+type announcer struct {
+	peer   PeerID
+	sentry sentry.SentryClient
+	eth66  bool // eth/65 requests don't have request id, their responses are matched by peer
+}
+
+func peerKey(peer PeerID) string { return string(gointerfaces.ConvertH512ToBytes(peer)) }
+
+// pooledTxsRequest - GET_POOLED_TRANSACTIONS request sent to a peer, which is waiting for response
+type pooledTxsRequest struct {
+	id       uint64
+	to       announcer
+	hashes   Hashes
+	pending  map[string]struct{} // requested hashes, which are not delivered yet
+	deadline time.Time
+}
+
+// announcedTx - unknown transaction announced by peers
+type announcedTx struct {
+	next      []announcer         // announcers which were not asked yet, in order of announcement
+	seen      map[string]struct{} // peerKey of all announcers - to not ask same peer twice
+	requestID uint64              // request waiting for response, 0 - transaction is not requested now
+}
+
+// pooledTxsRequests - state machine of fetching announced transactions. Every unknown hash is requested from
+// one peer at a time; if the peer doesn't deliver it (timeout or incomplete response), it's requested from the
+// next announcer, until all announcers are asked. Methods return requests which caller must send
+type pooledTxsRequests struct {
+	lock      sync.Mutex
+	lastID    uint64
+	announced map[string]*announcedTx      // tx_hash => announcers
+	requests  map[uint64]*pooledTxsRequest // requestID => request
+}
+
+func newPooledTxsRequests() *pooledTxsRequests {
+	return &pooledTxsRequests{announced: map[string]*announcedTx{}, requests: map[uint64]*pooledTxsRequest{}}
+}
+
+// announce - remembers peer as announcer of unknown hashes. Hashes which are not requested from any peer yet,
+// are requested from this one
+func (r *pooledTxsRequests) announce(from announcer, unknownHashes Hashes, now time.Time) []*pooledTxsRequest {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := peerKey(from.peer)
+	var toRequest Hashes
+	for i := 0; i < unknownHashes.Len(); i++ {
+		hash := unknownHashes.At(i)
+		a, ok := r.announced[string(hash)]
+		if !ok {
+			a = &announcedTx{seen: map[string]struct{}{}}
+			r.announced[string(hash)] = a
+		}
+		if _, ok = a.seen[key]; ok {
+			continue
+		}
+		a.seen[key] = struct{}{}
+		if a.requestID != 0 {
+			a.next = append(a.next, from)
+			continue
+		}
+		toRequest = append(toRequest, hash...)
+	}
+	return r.newRequests(from, toRequest, now)
+}
+
+// delivered - processes response: transactions with deliveredHashes came from peer as response to request
+// requestID (nil for eth/65, then the oldest request to the peer is used). Requested transactions which are not
+// delivered are requested from next announcers. Returns false if response is unsolicited or has transactions
+// which were not requested
+func (r *pooledTxsRequests) delivered(peer PeerID, requestID *uint64, deliveredHashes Hashes, now time.Time) (retry []*pooledTxsRequest, ok bool) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	key := peerKey(peer)
+	var req *pooledTxsRequest
+	if requestID != nil {
+		req = r.requests[*requestID]
+		if req != nil && peerKey(req.to.peer) != key {
+			req = nil
+		}
+	} else {
+		for _, candidate := range r.requests {
+			if peerKey(candidate.to.peer) == key && (req == nil || candidate.id < req.id) {
+				req = candidate
+			}
+		}
+	}
+
+	ok = req != nil
+	for i := 0; i < deliveredHashes.Len(); i++ {
+		hash := deliveredHashes.At(i)
+		if req == nil {
+			continue
+		}
+		if _, requested := req.pending[string(hash)]; !requested {
+			ok = false
+			continue
+		}
+		delete(req.pending, string(hash))
+		// unsolicited hashes may be requested from other peer, their requests stay untouched
+		if a, found := r.announced[string(hash)]; found && a.requestID == req.id {
+			delete(r.announced, string(hash))
+		}
+	}
+	if req == nil {
+		return nil, ok
+	}
+	delete(r.requests, req.id)
+	return r.reassign(req, now), ok
+}
+
+// forget - transactions became known by other way (for example broadcast by TRANSACTIONS message)
+func (r *pooledTxsRequests) forget(hashes Hashes) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for i := 0; i < hashes.Len(); i++ {
+		delete(r.announced, string(hashes.At(i)))
+	}
+}
+
+// expire - requests without response until deadline are considered failed, their hashes are requested from
+// next announcers
+func (r *pooledTxsRequests) expire(now time.Time) (retry []*pooledTxsRequest) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	for id, req := range r.requests {
+		if now.Before(req.deadline) {
+			continue
+		}
+		delete(r.requests, id)
+		retry = append(retry, r.reassign(req, now)...)
+	}
+	return retry
+}
+
+// reassign - requests undelivered hashes of failed request from next announcers, hashes without announcers
+// left are forgotten. Must be called under lock
+func (r *pooledTxsRequests) reassign(failed *pooledTxsRequest, now time.Time) (retry []*pooledTxsRequest) {
+	byPeer := map[string]Hashes{}
+	announcers := map[string]announcer{}
+	for i := 0; i < failed.hashes.Len(); i++ {
+		hash := failed.hashes.At(i)
+		if _, ok := failed.pending[string(hash)]; !ok {
+			continue
+		}
+		a, ok := r.announced[string(hash)]
+		if !ok || a.requestID != failed.id {
+			continue
+		}
+		if len(a.next) == 0 {
+			delete(r.announced, string(hash))
+			continue
+		}
+		next := a.next[0]
+		a.next = a.next[1:]
+		a.requestID = 0
+		key := peerKey(next.peer)
+		announcers[key] = next
+		byPeer[key] = append(byPeer[key], hash...)
+	}
+	for key, hashes := range byPeer {
+		retry = append(retry, r.newRequests(announcers[key], hashes, now)...)
+	}
+	return retry
+}
+
+// newRequests - splits hashes to requests of limited size. Must be called under lock
+func (r *pooledTxsRequests) newRequests(to announcer, hashes Hashes, now time.Time) (reqs []*pooledTxsRequest) {
+	for len(hashes) > 0 {
+		chunk := hashes
+		if chunk.Len() > maxPooledTxsRequestHashes {
+			chunk = hashes[:maxPooledTxsRequestHashes*32]
+		}
+		hashes = hashes[len(chunk):]
+
+		r.lastID++
+		req := &pooledTxsRequest{id: r.lastID, to: to, hashes: chunk, pending: make(map[string]struct{}, chunk.Len()), deadline: now.Add(pooledTxsRequestTimeout)}
+		for i := 0; i < chunk.Len(); i++ {
+			req.pending[string(chunk.At(i))] = struct{}{}
+			r.announced[string(chunk.At(i))].requestID = req.id
+		}
+		r.requests[req.id] = req
+		reqs = append(reqs, req)
+	}
+	return reqs
+}
@@ -0,0 +1,81 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPooledTxsRequests(t *testing.T) {
+	require := require.New(t)
+	peer := func(i byte) announcer {
+		return announcer{peer: gointerfaces.ConvertBytesToH512([]byte{i, 63: 0}), eth66: true}
+	}
+	p1, p2, p3 := peer(1), peer(2), peer(3)
+	h1, h2, h3 := toHashes([32]byte{1}), toHashes([32]byte{2}), toHashes([32]byte{3})
+	now := time.Now()
+	r := newPooledTxsRequests()
+
+	reqs := r.announce(p1, toHashes([32]byte{1}, [32]byte{2}), now)
+	require.Len(reqs, 1)
+	require.Equal(p1, reqs[0].to)
+	first := reqs[0].id
+	// hashes already requested are not requested again, but p2 and p3 become fallback
+	reqs = r.announce(p2, toHashes([32]byte{1}, [32]byte{2}, [32]byte{3}), now)
+	require.Len(reqs, 1)
+	require.Equal(h3, reqs[0].hashes)
+	require.NotEqual(first, reqs[0].id)
+	require.Empty(r.announce(p3, h1, now))
+	require.Empty(r.announce(p1, h1, now))
+
+	// p1 delivered only h1 - h2 is requested from p2 right away
+	retry, ok := r.delivered(p1.peer, &first, h1, now)
+	require.True(ok)
+	require.Len(retry, 1)
+	require.Equal(p2, retry[0].to)
+	require.Equal(h2, retry[0].hashes)
+
+	// p2 doesn't answer - after timeout h2 has no announcers left and is forgotten, h3 - too
+	require.Empty(r.expire(now.Add(pooledTxsRequestTimeout - time.Millisecond)))
+	require.Empty(r.expire(now.Add(pooledTxsRequestTimeout)))
+	require.Empty(r.announced)
+	require.Empty(r.requests)
+
+	// timeout of a request moves hash to the next announcer
+	r.announce(p1, h1, now)
+	r.announce(p3, h1, now)
+	retry = r.expire(now.Add(pooledTxsRequestTimeout))
+	require.Len(retry, 1)
+	require.Equal(p3, retry[0].to)
+	late := retry[0].id
+
+	// response of other peer and response with not requested transactions are reported, they don't affect
+	// request of the hash
+	_, ok = r.delivered(p1.peer, &late, h1, now)
+	require.False(ok)
+	_, ok = r.delivered(p1.peer, nil, h1, now)
+	require.False(ok)
+	require.Equal(late, r.announced[string(h1)].requestID)
+	_, ok = r.delivered(p3.peer, &late, toHashes([32]byte{1}, [32]byte{4}), now)
+	require.False(ok)
+	require.Empty(r.announced)
+	require.Empty(r.requests)
+}
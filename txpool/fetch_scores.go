@@ -0,0 +1,135 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Misbehaviour scores: peer is kicked when its score reaches kickScore. Scores are halved every
+// scoreDecayEvery, so rare mistakes of honest peers (for example transaction they didn't validate) are forgiven
+const (
+	kickScore       = 100
+	scoreDecayEvery = time.Minute
+	kickedPeerTTL   = 10 * time.Minute // kicked peer is forgotten even if sentry doesn't report its disconnect
+
+	invalidMessageScore           = 20 // message can't be parsed at all
+	invalidTxScore                = 5  // transaction can't be parsed, has invalid signature or other chain id
+	unsolicitedTxsScore           = 10 // POOLED_TRANSACTIONS which were not requested, or were requested from other peer
+	oversizedRequestScore         = 20 // GET_POOLED_TRANSACTIONS with more than maxPooledTxsRequestHashes hashes
+	requestFloodScore             = 10 // every GET_POOLED_TRANSACTIONS over maxPooledTxsRequestsPerSecond
+	maxPooledTxsRequestsPerSecond = 20
+)
+
+// peerScores - misbehaviour of peers, and rate of their GET_POOLED_TRANSACTIONS requests
+type peerScores struct {
+	lock     sync.Mutex
+	scores   map[string]int            // peerKey => score
+	requests map[string]*requestWindow // peerKey => requests received in current second
+	kicked   map[string]time.Time      // peerKey => time of kick, until sentry reports disconnect or kickedPeerTTL passes
+}
+
+type requestWindow struct {
+	start time.Time
+	count int
+}
+
+func newPeerScores() *peerScores {
+	return &peerScores{scores: map[string]int{}, requests: map[string]*requestWindow{}, kicked: map[string]time.Time{}}
+}
+
+// add - increases score of peer, returns true if peer must be kicked. Score of kicked peer starts from zero
+func (s *peerScores) add(peer PeerID, score int) (kick bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := peerKey(peer)
+	s.scores[key] += score
+	if s.scores[key] < kickScore {
+		return false
+	}
+	delete(s.scores, key)
+	s.kicked[key] = time.Now()
+	return true
+}
+
+// isKicked - peer was kicked and sentry didn't report its disconnect yet
+func (s *peerScores) isKicked(peer PeerID) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, ok := s.kicked[peerKey(peer)]
+	return ok
+}
+
+// request - counts GET_POOLED_TRANSACTIONS request of peer, returns false if peer exceeded
+// maxPooledTxsRequestsPerSecond
+func (s *peerScores) request(peer PeerID, now time.Time) (allowed bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	key := peerKey(peer)
+	w, ok := s.requests[key]
+	if !ok || now.Sub(w.start) >= time.Second {
+		w = &requestWindow{start: now}
+		s.requests[key] = w
+	}
+	w.count++
+	return w.count <= maxPooledTxsRequestsPerSecond
+}
+
+// decay - halves all scores, forgets peers with zero score, finished request windows and peers kicked more than
+// kickedPeerTTL ago
+func (s *peerScores) decay(now time.Time) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for key, score := range s.scores {
+		if score /= 2; score == 0 {
+			delete(s.scores, key)
+		} else {
+			s.scores[key] = score
+		}
+	}
+	for key, w := range s.requests {
+		if now.Sub(w.start) >= time.Second {
+			delete(s.requests, key)
+		}
+	}
+	for key, kickedAt := range s.kicked {
+		if now.Sub(kickedAt) >= kickedPeerTTL {
+			delete(s.kicked, key)
+		}
+	}
+}
+
+func (s *peerScores) remove(peer PeerID) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.scores, peerKey(peer))
+	delete(s.requests, peerKey(peer))
+	delete(s.kicked, peerKey(peer))
+}
+
+// snapshot - hex encoded peer id => score, for diagnostics
+func (s *peerScores) snapshot() map[string]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	scores := make(map[string]int, len(s.scores))
+	for key, score := range s.scores {
+		scores[fmt.Sprintf("%x", key)] = score
+	}
+	return scores
+}
@@ -0,0 +1,70 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPeerScores(t *testing.T) {
+	require := require.New(t)
+	peers := toPeerIDs(1, 2)
+	s := newPeerScores()
+
+	require.False(s.add(peers[0], kickScore-invalidTxScore-1))
+	require.False(s.add(peers[1], invalidTxScore))
+	require.Equal(map[string]int{
+		fmt.Sprintf("%x", peerKey(peers[0])): kickScore - invalidTxScore - 1,
+		fmt.Sprintf("%x", peerKey(peers[1])): invalidTxScore,
+	}, s.snapshot())
+
+	// decay halves scores, so the first peer isn't kicked yet
+	s.decay(time.Now())
+	require.False(s.add(peers[0], invalidTxScore))
+	require.Equal((kickScore-invalidTxScore-1)/2+invalidTxScore, s.snapshot()[fmt.Sprintf("%x", peerKey(peers[0]))])
+	require.True(s.add(peers[0], kickScore/2))
+	// kicked peer starts from zero, it's kicked until removed
+	require.NotContains(s.snapshot(), fmt.Sprintf("%x", peerKey(peers[0])))
+	require.True(s.isKicked(peers[0]))
+	require.False(s.isKicked(peers[1]))
+	s.remove(peers[0])
+	require.False(s.isKicked(peers[0]))
+	// kicked peer is forgotten after kickedPeerTTL, if sentry doesn't report its disconnect
+	require.True(s.add(peers[1], kickScore))
+	s.decay(time.Now().Add(kickedPeerTTL - time.Second))
+	require.True(s.isKicked(peers[1]))
+	s.decay(time.Now().Add(kickedPeerTTL))
+	require.False(s.isKicked(peers[1]))
+
+	// peer which misbehaves rarely is forgotten
+	s.decay(time.Now())
+	s.decay(time.Now())
+	s.decay(time.Now())
+	require.Empty(s.snapshot())
+
+	now := time.Now()
+	for i := 0; i < maxPooledTxsRequestsPerSecond; i++ {
+		require.True(s.request(peers[0], now))
+	}
+	require.False(s.request(peers[0], now.Add(time.Second-time.Millisecond)))
+	require.True(s.request(peers[1], now))
+	require.True(s.request(peers[0], now.Add(time.Second)))
+}
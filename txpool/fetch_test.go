@@ -22,6 +22,7 @@ import (
 	"sync"
 	"testing"
 
+	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/direct"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/types"
@@ -37,13 +38,13 @@ func TestFetch(t *testing.T) {
 
 	var genesisHash [32]byte
 	var networkId uint64 = 1
-	forks := []uint64{1, 5, 10}
+	chain := ChainConfig{Forks: []uint64{1, 5, 10}}
 
 	m := NewMockSentry(ctx)
 	sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
 	pool := &PoolMock{}
 
-	fetch := NewFetch(ctx, []sentry.SentryClient{sentryClient}, genesisHash, networkId, forks, pool, logger)
+	fetch := NewFetch(ctx, []sentry.SentryClient{sentryClient}, genesisHash, networkId, chain, *uint256.NewInt(networkId), pool, NewPeers(), logger)
 	var wg sync.WaitGroup
 	fetch.SetWaitGroup(&wg)
 	m.StreamWg.Add(2)
@@ -65,52 +66,212 @@ func TestFetch(t *testing.T) {
 
 }
 
+func TestFetchPooledTransactions(t *testing.T) {
+	logger := log.New()
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+
+	var genesisHash [32]byte
+	m := NewMockSentry(ctx)
+	sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
+	pool := &PoolMock{}
+
+	fetch := NewFetch(ctx, []sentry.SentryClient{sentryClient}, genesisHash, 1, ChainConfig{Forks: []uint64{1, 5, 10}}, *uint256.NewInt(123), pool, NewPeers(), logger)
+	var wg sync.WaitGroup
+	fetch.SetWaitGroup(&wg)
+	m.StreamWg.Add(2)
+	fetch.Start()
+	m.StreamWg.Wait()
+
+	txsRlp := [][]byte{decodeHex(txParseTests[0].payloadStr), decodeHex(txParseTests[2].payloadStr)}
+	wg.Add(2)
+	for _, req := range []*sentry.InboundMessage{
+		{Id: sentry.MessageId_POOLED_TRANSACTIONS_66, Data: EncodePooledTransactions66(txsRlp, 1, nil), PeerId: PeerId},
+		{Id: sentry.MessageId_TRANSACTIONS_66, Data: EncodePooledTransactions65(txsRlp, nil), PeerId: PeerId},
+	} {
+		for i, err := range m.Send(req) {
+			require.NoError(t, err, "sending %s (%d)", req.Id, i)
+		}
+	}
+	wg.Wait()
+
+	calls := pool.OnNewTxsCalls()
+	require.Equal(t, 2, len(calls))
+	for _, call := range calls {
+		require.Equal(t, 2, len(call.NewTxs.txs))
+		require.Equal(t, decodeHex(txParseTests[0].idHashStr), call.NewTxs.txs[0].idHash[:])
+		require.Equal(t, decodeHex(txParseTests[2].idHashStr), call.NewTxs.txs[1].idHash[:])
+	}
+}
+
+func TestFetchPenalizesPeer(t *testing.T) {
+	require := require.New(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	m := NewMockSentry(ctx)
+	sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
+	pool := &PoolMock{}
+	fetch := NewFetch(ctx, []sentry.SentryClient{sentryClient}, [32]byte{}, 1, ChainConfig{}, *uint256.NewInt(1), pool, NewPeers(), log.New())
+
+	invalid := &sentry.InboundMessage{Id: sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, Data: decodeHex("c3010203"), PeerId: PeerId}
+	for i := 1; i < kickScore/invalidMessageScore; i++ {
+		require.Error(fetch.handleInboundMessage(invalid, sentryClient))
+		require.Equal(map[string]int{fmt.Sprintf("%x", peerKey(PeerId)): i * invalidMessageScore}, fetch.PeerScores())
+	}
+	require.Empty(m.PenalizePeerCalls())
+
+	require.Error(fetch.handleInboundMessage(invalid, sentryClient))
+	calls := m.PenalizePeerCalls()
+	require.Len(calls, 1)
+	require.Equal(peerKey(PeerId), peerKey(calls[0].PenalizePeerRequest.PeerId))
+	require.Equal(sentry.PenaltyKind_Kick, calls[0].PenalizePeerRequest.Penalty)
+	require.Empty(fetch.PeerScores())
+	require.Equal(0, fetch.peers.Len())
+
+	// unsolicited pooled transactions are misbehaviour too
+	pool.OnNewTxsFunc = func(newTxs TxSlots) error { return nil }
+	unsolicited := &sentry.InboundMessage{Id: sentry.MessageId_POOLED_TRANSACTIONS_66, Data: decodeHex("c482045cc0"), PeerId: PeerId}
+	require.NoError(fetch.handleInboundMessage(unsolicited, sentryClient))
+	require.Equal(map[string]int{fmt.Sprintf("%x", peerKey(PeerId)): unsolicitedTxsScore}, fetch.PeerScores())
+	// kicked peer isn't connected again by its messages, until sentry reports disconnect
+	require.Equal(0, fetch.peers.Len())
+	require.NoError(fetch.handleNewPeer(&sentry.PeersReply{PeerId: PeerId, Event: sentry.PeersReply_Disconnect}, sentryClient))
+	require.NoError(fetch.handleInboundMessage(unsolicited, sentryClient))
+	require.Equal(1, fetch.peers.Len())
+}
+
+func TestFetchForkRules(t *testing.T) {
+	require := require.New(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	// same chain config is given to the pool and to the fetch, London is activated at block 20
+	chain := ChainConfig{Forks: []uint64{10, 20}, BerlinBlock: 10, LondonBlock: 20}
+	cfg := DefaultConfig
+	cfg.Chain = chain
+	pool := newTestPool(t, cfg, nil, headerWithBaseFee(18, 0))
+	events, unsubscribe := pool.Subscribe(10)
+	defer unsubscribe()
+	m := NewMockSentry(ctx)
+	sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
+	fetch := NewFetch(ctx, []sentry.SentryClient{sentryClient}, [32]byte{}, 1, chain, *uint256.NewInt(123), pool, NewPeers(), log.New())
+	require.Equal(chain.Forks, fetch.statusData.ForkData.Forks)
+
+	// pending block 19 is before London: dynamic fee transaction is rejected, legacy one is added
+	txsRlp := [][]byte{decodeHex(txParseTests[1].payloadStr), decodeHex(txParseTests[2].payloadStr)}
+	msg := &sentry.InboundMessage{Id: sentry.MessageId_TRANSACTIONS_66, Data: EncodePooledTransactions65(txsRlp, nil), PeerId: PeerId}
+	require.NoError(fetch.handleInboundMessage(msg, sentryClient))
+	got := map[[32]byte]Event{}
+	for len(got) < 2 {
+		ev := <-events
+		got[ev.Hash] = ev
+	}
+	require.Equal(TxAdded, got[toHash(txParseTests[1].idHashStr)].Kind)
+	require.Equal(TxRejected, got[toHash(txParseTests[2].idHashStr)].Kind)
+	require.Equal(TxTypeNotSupported, got[toHash(txParseTests[2].idHashStr)].Reason)
+	require.False(pool.IdHashKnown(decodeHex(txParseTests[2].idHashStr)))
+
+	// fork schedule which doesn't match the forks of handshake is rejected
+	cfg.Chain.LondonBlock = 21
+	_, err := New(make(chan Hashes, 10), cfg, nil)
+	require.Error(err)
+}
+
 func TestSendTxPropagate(t *testing.T) {
 	logger := log.New()
 
 	ctx, cancelFn := context.WithCancel(context.Background())
 	defer cancelFn()
+	pool := &PoolMock{GetRlpFunc: func(hash []byte) []byte { return append([]byte{0xe0}, hash...) }}
+	countByID := func(calls []struct {
+		ContextMoqParam        context.Context
+		SendMessageByIdRequest *sentry.SendMessageByIdRequest
+	}) map[sentry.MessageId]int {
+		counts := map[sentry.MessageId]int{}
+		for _, call := range calls {
+			counts[call.SendMessageByIdRequest.Data.Id]++
+		}
+		return counts
+	}
 	t.Run("few remote byHash", func(t *testing.T) {
 		m := NewMockSentry(ctx)
-		send := NewSend(ctx, []SentryClient{direct.NewSentryClientDirect(direct.ETH66, m)}, nil, logger)
+		sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
+		peers := NewPeers()
+		peerIDs := toPeerIDs(1, 2)
+		peers.Add(peerIDs[0], sentryClient)
+		peers.Add(peerIDs[1], sentryClient)
+		// second peer announced one of hashes to us
+		peers.MarkKnown(peerIDs[1], toHashes([32]byte{1}))
+		send := NewSend(ctx, []SentryClient{sentryClient}, pool, peers, logger)
 		send.BroadcastRemotePooledTxs(toHashes([32]byte{1}, [32]byte{42}))
 
-		calls := m.SendMessageToRandomPeersCalls()
-		require.Equal(t, 1, len(calls))
-		first := calls[0].SendMessageToRandomPeersRequest.Data
-		assert.Equal(t, sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, first.Id)
-		assert.Equal(t, 68, len(first.Data))
+		// sqrt(2) peers receive full transactions, the rest - hashes
+		calls := m.SendMessageByIdCalls()
+		require.Equal(t, 2, len(calls))
+		require.Equal(t, map[sentry.MessageId]int{sentry.MessageId_TRANSACTIONS_66: 1, sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66: 1}, countByID(calls))
+		for _, call := range calls {
+			// both full transactions and hashes are 33 bytes each in this test, second peer already knows one
+			req := call.SendMessageByIdRequest
+			if peerKey(req.PeerId) == peerKey(peerIDs[1]) {
+				assert.Equal(t, 34, len(req.Data.Data))
+			} else {
+				assert.Equal(t, 68, len(req.Data.Data))
+			}
+		}
+
+		// peers remember what they received
+		send.BroadcastRemotePooledTxs(toHashes([32]byte{1}, [32]byte{42}))
+		require.Equal(t, 2, len(m.SendMessageByIdCalls()))
 	})
 	t.Run("much remote byHash", func(t *testing.T) {
 		m := NewMockSentry(ctx)
-		send := NewSend(ctx, []SentryClient{direct.NewSentryClientDirect(direct.ETH66, m)}, nil, logger)
+		sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
+		peers := NewPeers()
+		peers.Add(toPeerIDs(1)[0], sentryClient)
+		send := NewSend(ctx, []SentryClient{sentryClient}, pool, peers, logger)
 		list := make(Hashes, p2pTxPacketLimit*3)
 		for i := 0; i < len(list); i += 32 {
 			b := []byte(fmt.Sprintf("%x", i))
 			copy(list[i:i+32], b)
 		}
 		send.BroadcastRemotePooledTxs(list)
-		calls := m.SendMessageToRandomPeersCalls()
-		require.Equal(t, 3, len(calls))
-		for i := 0; i < 3; i++ {
-			call := calls[i].SendMessageToRandomPeersRequest.Data
-			require.Equal(t, sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, call.Id)
+		// only peer receives full transactions, split by size of RLP
+		calls := m.SendMessageByIdCalls()
+		require.Equal(t, 4, len(calls))
+		for i := 0; i < 4; i++ {
+			call := calls[i].SendMessageByIdRequest.Data
+			require.Equal(t, sentry.MessageId_TRANSACTIONS_66, call.Id)
 			require.True(t, len(call.Data) > 0)
 		}
 	})
 	t.Run("few local byHash", func(t *testing.T) {
 		m := NewMockSentry(ctx)
-		m.SendMessageToAllFunc = func(contextMoqParam context.Context, outboundMessageData *sentry.OutboundMessageData) (*sentry.SentPeers, error) {
-			return &sentry.SentPeers{Peers: make([]*types.H512, 5)}, nil
+		sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
+		peers := NewPeers()
+		for _, peerID := range toPeerIDs(1, 2, 3, 4, 5) {
+			peers.Add(peerID, sentryClient)
 		}
-		send := NewSend(ctx, []SentryClient{direct.NewSentryClientDirect(direct.ETH66, m)}, nil, logger)
-		send.BroadcastLocalPooledTxs(toHashes([32]byte{1}, [32]byte{42}))
-
-		calls := m.SendMessageToAllCalls()
-		require.Equal(t, 1, len(calls))
-		first := calls[0].OutboundMessageData
-		assert.Equal(t, sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, first.Id)
-		assert.Equal(t, 68, len(first.Data))
+		send := NewSend(ctx, []SentryClient{sentryClient}, pool, peers, logger)
+		require.Equal(t, 5, send.BroadcastLocalPooledTxs(toHashes([32]byte{1}, [32]byte{42})))
+
+		calls := m.SendMessageByIdCalls()
+		require.Equal(t, 5, len(calls))
+		require.Equal(t, map[sentry.MessageId]int{sentry.MessageId_TRANSACTIONS_66: 2, sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66: 3}, countByID(calls))
+		for _, call := range calls {
+			if call.SendMessageByIdRequest.Data.Id == sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66 {
+				assert.Equal(t, 68, len(call.SendMessageByIdRequest.Data.Data))
+			}
+		}
+
+		// disconnected peer doesn't receive anything
+		peers.Remove(toPeerIDs(1)[0])
+		require.Equal(t, 4, send.BroadcastLocalPooledTxs(toHashes([32]byte{43})))
+
+		// re-announcement reaches peers which already know the hashes
+		require.Equal(t, 0, send.BroadcastLocalPooledTxs(toHashes([32]byte{43})))
+		sent := len(m.SendMessageByIdCalls())
+		require.Equal(t, 4, send.RebroadcastLocalPooledTxs(toHashes([32]byte{43})))
+		calls = m.SendMessageByIdCalls()[sent:]
+		require.Equal(t, map[sentry.MessageId]int{sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66: 4}, countByID(calls))
 	})
 	t.Run("sync with new peer", func(t *testing.T) {
 		m := NewMockSentry(ctx)
@@ -118,7 +279,7 @@ func TestSendTxPropagate(t *testing.T) {
 		m.SendMessageToAllFunc = func(contextMoqParam context.Context, outboundMessageData *sentry.OutboundMessageData) (*sentry.SentPeers, error) {
 			return &sentry.SentPeers{Peers: make([]*types.H512, 5)}, nil
 		}
-		send := NewSend(ctx, []SentryClient{direct.NewSentryClientDirect(direct.ETH66, m)}, nil, logger)
+		send := NewSend(ctx, []SentryClient{direct.NewSentryClientDirect(direct.ETH66, m)}, nil, NewPeers(), logger)
 		expectPeers := toPeerIDs(1, 2, 42)
 		send.PropagatePooledTxsToPeersList(expectPeers, toHashes([32]byte{1}, [32]byte{42}))
 
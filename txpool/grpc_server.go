@@ -0,0 +1,215 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// TxPoolAPIVersion - use it to track changes in API
+var TxPoolAPIVersion = &types.VersionReply{Major: 1, Minor: 0, Patch: 0}
+
+// TxpoolServer - implements Txpool gRPC service on top of TxPool, it allows RPC daemons to work
+// with standalone transaction pool process
+type TxpoolServer struct {
+	txpool_proto.UnimplementedTxpoolServer // must be embedded to have forward compatible implementations.
+
+	ctx           context.Context
+	txPool        *TxPool
+	newTxsStreams *NewTxsStreams
+
+	parseCtx     *TxParseContext
+	parseCtxLock sync.Mutex // parse context is not thread-safe, but gRPC methods are called concurrently
+}
+
+// NewTxpoolServer - newTxsStreams must be also passed to BroadcastLoop, which feeds OnAdd subscribers
+func NewTxpoolServer(ctx context.Context, txPool *TxPool, newTxsStreams *NewTxsStreams) *TxpoolServer {
+	return &TxpoolServer{ctx: ctx, txPool: txPool, newTxsStreams: newTxsStreams, parseCtx: NewTxParseContext().WithChainID(txPool.cfg.ChainID)}
+}
+
+// Version returns the service-side interface version number
+func (s *TxpoolServer) Version(context.Context, *emptypb.Empty) (*types.VersionReply, error) {
+	return TxPoolAPIVersion, nil
+}
+
+func (s *TxpoolServer) FindUnknown(ctx context.Context, in *txpool_proto.TxHashes) (*txpool_proto.TxHashes, error) {
+	reply := &txpool_proto.TxHashes{}
+	for _, h := range in.Hashes {
+		hash := gointerfaces.ConvertH256ToHash(h)
+		if s.txPool.IdHashKnown(hash[:]) {
+			continue
+		}
+		reply.Hashes = append(reply.Hashes, h)
+	}
+	return reply, nil
+}
+
+// Add - parses transactions and adds them to the pool as local ones. Preserves incoming order and amount:
+// every transaction gets ImportResult and error message (empty on success)
+func (s *TxpoolServer) Add(ctx context.Context, in *txpool_proto.AddRequest) (*txpool_proto.AddReply, error) {
+	reply := &txpool_proto.AddReply{
+		Imported: make([]txpool_proto.ImportResult, len(in.RlpTxs)),
+		Errors:   make([]string, len(in.RlpTxs)),
+	}
+	var slots TxSlots
+	var slotIdx []int // position in the request of every parsed transaction
+	s.parseCtxLock.Lock()
+	for i, rlp := range in.RlpTxs {
+		rlp = wrapTypedTx(rlp)
+		slot, sender, err := s.parseCtx.ParseSingleTransaction(rlp)
+		if err != nil {
+			reply.Imported[i] = txpool_proto.ImportResult_INVALID
+			reply.Errors[i] = err.Error()
+			continue
+		}
+		slots.Append(slot, sender[:], true)
+		slotIdx = append(slotIdx, i)
+	}
+	s.parseCtxLock.Unlock()
+	if len(slots.txs) == 0 {
+		return reply, nil
+	}
+
+	reasons, err := s.txPool.AddLocals(ctx, slots)
+	if err != nil {
+		return nil, err
+	}
+	for j, reason := range reasons {
+		i := slotIdx[j]
+		reply.Imported[i] = mapDiscardReasonToProto(reason)
+		if reason != Success {
+			reply.Errors[i] = reason.String()
+		}
+	}
+	return reply, nil
+}
+
+func mapDiscardReasonToProto(reason DiscardReason) txpool_proto.ImportResult {
+	switch reason {
+	case Success:
+		return txpool_proto.ImportResult_SUCCESS
+	case AlreadyKnown:
+		return txpool_proto.ImportResult_ALREADY_EXISTS
+	case ReplaceUnderpriced:
+		return txpool_proto.ImportResult_REPLACEMENT_UNDERPRICED
+	case FeeTooLow:
+		return txpool_proto.ImportResult_FEE_TOO_LOW
+	// Replaced - superseded by transaction with same nonce later in the same batch
+	case NonceTooLow, Mined, Replaced, PrivateExpired, LifetimeExpired:
+		return txpool_proto.ImportResult_STALE
+	case OversizedData, IntrinsicGas, GasLimitTooHigh, TipAboveFeeCap, TxTypeNotSupported, PolicyRejected:
+		return txpool_proto.ImportResult_INVALID
+	// no room for the transaction: higher fee doesn't necessarily help, it's not a problem of the transaction either,
+	// but proto has no better result. Errors explain the reason
+	case PendingPoolOverflow, BaseFeePoolOverflow, QueuedPoolOverflow, SenderPoolOverflow:
+		return txpool_proto.ImportResult_INVALID
+	default: // NotSet
+		return txpool_proto.ImportResult_INTERNAL_ERROR
+	}
+}
+
+func (s *TxpoolServer) Transactions(ctx context.Context, in *txpool_proto.TransactionsRequest) (*txpool_proto.TransactionsReply, error) {
+	reply := &txpool_proto.TransactionsReply{RlpTxs: make([][]byte, len(in.Hashes))}
+	for i, h := range in.Hashes {
+		hash := gointerfaces.ConvertH256ToHash(h)
+		reply.RlpTxs[i] = s.txPool.GetRlpWithPrivate(hash[:])
+	}
+	return reply, nil
+}
+
+// All - returns all transactions of the pool: pending sub-pool as PENDING, baseFee and queued sub-pools as QUEUED,
+// with exact sub-pool and markers. Senders of the transactions are returned with their state and pending nonce
+func (s *TxpoolServer) All(ctx context.Context, _ *txpool_proto.AllRequest) (*txpool_proto.AllReply, error) {
+	reply := &txpool_proto.AllReply{}
+	for _, sender := range s.txPool.Content() {
+		reply.Senders = append(reply.Senders, &txpool_proto.AllReply_Sender{
+			Address:      sender.Address[:],
+			Nonce:        sender.Nonce,
+			Balance:      gointerfaces.ConvertUint256IntToH256(&sender.Balance),
+			PendingNonce: sender.PendingNonce,
+		})
+		for _, tx := range sender.Txs {
+			txType := txpool_proto.AllReply_QUEUED
+			if tx.SubPool == PendingSubPool {
+				txType = txpool_proto.AllReply_PENDING
+			}
+			reply.Txs = append(reply.Txs, &txpool_proto.AllReply_Tx{
+				Type:          txType,
+				Sender:        sender.Address[:],
+				RlpTx:         tx.Rlp,
+				SubPool:       uint32(tx.SubPool),
+				SubPoolMarker: uint32(tx.Marker),
+			})
+		}
+	}
+	return reply, nil
+}
+
+// OnAdd - streams RLP of transactions added to the pool, until client or server context is cancelled
+func (s *TxpoolServer) OnAdd(req *txpool_proto.OnAddRequest, stream txpool_proto.Txpool_OnAddServer) error {
+	remove := s.newTxsStreams.Add(stream)
+	defer remove()
+	select {
+	case <-stream.Context().Done():
+		return stream.Context().Err()
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// NewTxsStreams - keeps subscribers of OnAdd, it's safe to use this class as non-pointer
+type NewTxsStreams struct {
+	lock    sync.Mutex
+	id      uint
+	streams map[uint]txpool_proto.Txpool_OnAddServer
+}
+
+// Add - subscribes stream, returned function unsubscribes it
+func (s *NewTxsStreams) Add(stream txpool_proto.Txpool_OnAddServer) (remove func()) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.streams == nil {
+		s.streams = map[uint]txpool_proto.Txpool_OnAddServer{}
+	}
+	s.id++
+	id := s.id
+	s.streams[id] = stream
+	return func() { s.remove(id) }
+}
+
+func (s *NewTxsStreams) remove(id uint) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	delete(s.streams, id)
+}
+
+// Broadcast - sends reply to all subscribers, subscribers which failed to receive it are removed
+func (s *NewTxsStreams) Broadcast(reply *txpool_proto.OnAddReply) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for id, stream := range s.streams {
+		if err := stream.Send(reply); err != nil {
+			delete(s.streams, id)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+func TestTxpoolServer(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// sender of txParseTests[2] has already used nonce of its transaction
+	staleSender := toAddr(txParseTests[2].senderStr)
+	newServer := func(protocolBaseFee uint64) *TxpoolServer {
+		pool := newTestPool(t, DefaultConfig, senderStateProviderMock(func(ctx context.Context, addr [20]byte) (uint64, uint256.Int, error) {
+			if addr == staleSender {
+				return 5, testBalance, nil
+			}
+			return 0, testBalance, nil
+		}), headerWithBaseFee(0, 1))
+		require.NoError(t, pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(0, 1), protocolBaseFee))
+		return NewTxpoolServer(ctx, pool, &NewTxsStreams{})
+	}
+
+	t.Run("add", func(t *testing.T) {
+		require := require.New(t)
+		s := newServer(1)
+		reply, err := s.Add(ctx, &txpool_proto.AddRequest{RlpTxs: [][]byte{
+			decodeHex(txParseTests[0].payloadStr),
+			decodeHex(txParseTests[0].payloadStr),
+			decodeHex("c3010203"),
+			decodeHex(txParseTests[2].payloadStr),
+		}})
+		require.NoError(err)
+		require.Equal([]txpool_proto.ImportResult{
+			txpool_proto.ImportResult_SUCCESS,
+			txpool_proto.ImportResult_ALREADY_EXISTS,
+			txpool_proto.ImportResult_INVALID,
+			txpool_proto.ImportResult_STALE,
+		}, reply.Imported)
+		require.Empty(reply.Errors[0])
+		require.NotEmpty(reply.Errors[2])
+
+		known := gointerfaces.ConvertHashToH256(toHash(txParseTests[0].idHashStr))
+		unknown := gointerfaces.ConvertHashToH256(toHash(txParseTests[2].idHashStr))
+		hashes, err := s.FindUnknown(ctx, &txpool_proto.TxHashes{Hashes: []*types.H256{known, unknown}})
+		require.NoError(err)
+		require.Equal([]*types.H256{unknown}, hashes.Hashes)
+
+		txs, err := s.Transactions(ctx, &txpool_proto.TransactionsRequest{Hashes: []*types.H256{unknown, known}})
+		require.NoError(err)
+		require.Equal(2, len(txs.RlpTxs))
+		require.Empty(txs.RlpTxs[0])
+		require.Equal(decodeHex(txParseTests[0].payloadStr), txs.RlpTxs[1])
+
+		all, err := s.All(ctx, &txpool_proto.AllRequest{})
+		require.NoError(err)
+		require.Equal(1, len(all.Txs))
+		require.Equal(txpool_proto.AllReply_PENDING, all.Txs[0].Type)
+		require.Equal(decodeHex(txParseTests[0].senderStr), all.Txs[0].Sender)
+		require.Equal(decodeHex(txParseTests[0].payloadStr), all.Txs[0].RlpTx)
+		require.Equal(uint32(PendingSubPool), all.Txs[0].SubPool)
+		require.True(SubPoolMarker(all.Txs[0].SubPoolMarker).Bits().IsLocal)
+		// sender of the stale transaction has no transactions in the pool
+		require.Equal(1, len(all.Senders))
+		require.Equal(decodeHex(txParseTests[0].senderStr), all.Senders[0].Address)
+		require.Equal(uint64(0), all.Senders[0].Nonce)
+		require.Equal(uint64(1), all.Senders[0].PendingNonce)
+	})
+	t.Run("typed transaction", func(t *testing.T) {
+		require := require.New(t)
+		s := newServer(1)
+		// canonical encoding, without RLP string prefix of p2p messages
+		canonical := decodeHex(txParseTests[3].payloadStr)[2:]
+		reply, err := s.Add(ctx, &txpool_proto.AddRequest{RlpTxs: [][]byte{canonical}})
+		require.NoError(err)
+		require.Equal([]txpool_proto.ImportResult{txpool_proto.ImportResult_SUCCESS}, reply.Imported)
+		hash := toHash(txParseTests[3].idHashStr)
+		require.True(s.txPool.IdHashKnown(hash[:]))
+	})
+	t.Run("fee too low", func(t *testing.T) {
+		s := newServer(1_000_000_000_000)
+		reply, err := s.Add(ctx, &txpool_proto.AddRequest{RlpTxs: [][]byte{decodeHex(txParseTests[0].payloadStr)}})
+		require.NoError(t, err)
+		require.Equal(t, []txpool_proto.ImportResult{txpool_proto.ImportResult_FEE_TOO_LOW}, reply.Imported)
+	})
+}
+
+func TestMapDiscardReasonToProto(t *testing.T) {
+	expected := map[DiscardReason]txpool_proto.ImportResult{
+		NotSet:              txpool_proto.ImportResult_INTERNAL_ERROR,
+		Success:             txpool_proto.ImportResult_SUCCESS,
+		AlreadyKnown:        txpool_proto.ImportResult_ALREADY_EXISTS,
+		Mined:               txpool_proto.ImportResult_STALE,
+		ReplaceUnderpriced:  txpool_proto.ImportResult_REPLACEMENT_UNDERPRICED,
+		FeeTooLow:           txpool_proto.ImportResult_FEE_TOO_LOW,
+		NonceTooLow:         txpool_proto.ImportResult_STALE,
+		PendingPoolOverflow: txpool_proto.ImportResult_INVALID,
+		BaseFeePoolOverflow: txpool_proto.ImportResult_INVALID,
+		QueuedPoolOverflow:  txpool_proto.ImportResult_INVALID,
+		Replaced:            txpool_proto.ImportResult_STALE,
+		OversizedData:       txpool_proto.ImportResult_INVALID,
+		IntrinsicGas:        txpool_proto.ImportResult_INVALID,
+		GasLimitTooHigh:     txpool_proto.ImportResult_INVALID,
+		TipAboveFeeCap:      txpool_proto.ImportResult_INVALID,
+		SenderPoolOverflow:  txpool_proto.ImportResult_INVALID,
+		TxTypeNotSupported:  txpool_proto.ImportResult_INVALID,
+		PolicyRejected:      txpool_proto.ImportResult_INVALID,
+		PrivateExpired:      txpool_proto.ImportResult_STALE,
+		LifetimeExpired:     txpool_proto.ImportResult_STALE,
+	}
+	// every reason which has a name must be in the table, new reasons must not silently map to INTERNAL_ERROR
+	for r := NotSet; !strings.HasPrefix(r.String(), "unknown discard reason"); r++ {
+		result, ok := expected[r]
+		require.True(t, ok, "reason %d (%s) is not in the table", r, r)
+		require.Equal(t, result, mapDiscardReasonToProto(r), r.String())
+	}
+}
+
+type onAddServerMock struct {
+	grpc.ServerStream
+	replies []*txpool_proto.OnAddReply
+}
+
+func (s *onAddServerMock) Send(reply *txpool_proto.OnAddReply) error {
+	s.replies = append(s.replies, reply)
+	return nil
+}
+
+func TestNewTxsStreams(t *testing.T) {
+	var streams NewTxsStreams
+	s1, s2 := &onAddServerMock{}, &onAddServerMock{}
+	remove1 := streams.Add(s1)
+	streams.Add(s2)
+	streams.Broadcast(&txpool_proto.OnAddReply{RplTxs: [][]byte{{1}}})
+	remove1()
+	streams.Broadcast(&txpool_proto.OnAddReply{RplTxs: [][]byte{{2}}})
+	require.Equal(t, 1, len(s1.replies))
+	require.Equal(t, 2, len(s2.replies))
+}
+
+func toHash(s string) (hash [32]byte) {
+	copy(hash[:], decodeHex(s))
+	return hash
+}
@@ -23,8 +23,11 @@ var _ Pool = &PoolMock{}
 // 			IdHashKnownFunc: func(hash []byte) bool {
 // 				panic("mock out the IdHashKnown method")
 // 			},
-// 			NotifyNewPeerFunc: func(peerID PeerID)  {
-// 				panic("mock out the NotifyNewPeer method")
+// 			OnNewPeerFunc: func(peerID PeerID)  {
+// 				panic("mock out the OnNewPeer method")
+// 			},
+// 			OnNewTxsFunc: func(newTxs TxSlots) error {
+// 				panic("mock out the OnNewTxs method")
 // 			},
 // 		}
 //
@@ -39,8 +42,11 @@ type PoolMock struct {
 	// IdHashKnownFunc mocks the IdHashKnown method.
 	IdHashKnownFunc func(hash []byte) bool
 
-	// NotifyNewPeerFunc mocks the NotifyNewPeer method.
-	NotifyNewPeerFunc func(peerID PeerID)
+	// OnNewPeerFunc mocks the OnNewPeer method.
+	OnNewPeerFunc func(peerID PeerID)
+
+	// OnNewTxsFunc mocks the OnNewTxs method.
+	OnNewTxsFunc func(newTxs TxSlots) error
 
 	// calls tracks calls to the methods.
 	calls struct {
@@ -54,15 +60,21 @@ type PoolMock struct {
 			// Hash is the hash argument value.
 			Hash []byte
 		}
-		// NotifyNewPeer holds details about calls to the NotifyNewPeer method.
-		NotifyNewPeer []struct {
+		// OnNewPeer holds details about calls to the OnNewPeer method.
+		OnNewPeer []struct {
 			// PeerID is the peerID argument value.
 			PeerID PeerID
 		}
+		// OnNewTxs holds details about calls to the OnNewTxs method.
+		OnNewTxs []struct {
+			// NewTxs is the newTxs argument value.
+			NewTxs TxSlots
+		}
 	}
-	lockGetRlp        sync.RWMutex
-	lockIdHashKnown   sync.RWMutex
-	lockNotifyNewPeer sync.RWMutex
+	lockGetRlp      sync.RWMutex
+	lockIdHashKnown sync.RWMutex
+	lockOnNewPeer   sync.RWMutex
+	lockOnNewTxs    sync.RWMutex
 }
 
 // GetRlp calls GetRlpFunc.
@@ -133,33 +145,67 @@ func (mock *PoolMock) IdHashKnownCalls() []struct {
 	return calls
 }
 
-// NotifyNewPeer calls NotifyNewPeerFunc.
-func (mock *PoolMock) NotifyNewPeer(peerID PeerID) {
+// OnNewPeer calls OnNewPeerFunc.
+func (mock *PoolMock) OnNewPeer(peerID PeerID) {
 	callInfo := struct {
 		PeerID PeerID
 	}{
 		PeerID: peerID,
 	}
-	mock.lockNotifyNewPeer.Lock()
-	mock.calls.NotifyNewPeer = append(mock.calls.NotifyNewPeer, callInfo)
-	mock.lockNotifyNewPeer.Unlock()
-	if mock.NotifyNewPeerFunc == nil {
+	mock.lockOnNewPeer.Lock()
+	mock.calls.OnNewPeer = append(mock.calls.OnNewPeer, callInfo)
+	mock.lockOnNewPeer.Unlock()
+	if mock.OnNewPeerFunc == nil {
 		return
 	}
-	mock.NotifyNewPeerFunc(peerID)
+	mock.OnNewPeerFunc(peerID)
 }
 
-// NotifyNewPeerCalls gets all the calls that were made to NotifyNewPeer.
+// OnNewPeerCalls gets all the calls that were made to OnNewPeer.
 // Check the length with:
-//     len(mockedPool.NotifyNewPeerCalls())
-func (mock *PoolMock) NotifyNewPeerCalls() []struct {
+//     len(mockedPool.OnNewPeerCalls())
+func (mock *PoolMock) OnNewPeerCalls() []struct {
 	PeerID PeerID
 } {
 	var calls []struct {
 		PeerID PeerID
 	}
-	mock.lockNotifyNewPeer.RLock()
-	calls = mock.calls.NotifyNewPeer
-	mock.lockNotifyNewPeer.RUnlock()
+	mock.lockOnNewPeer.RLock()
+	calls = mock.calls.OnNewPeer
+	mock.lockOnNewPeer.RUnlock()
+	return calls
+}
+
+// OnNewTxs calls OnNewTxsFunc.
+func (mock *PoolMock) OnNewTxs(newTxs TxSlots) error {
+	callInfo := struct {
+		NewTxs TxSlots
+	}{
+		NewTxs: newTxs,
+	}
+	mock.lockOnNewTxs.Lock()
+	mock.calls.OnNewTxs = append(mock.calls.OnNewTxs, callInfo)
+	mock.lockOnNewTxs.Unlock()
+	if mock.OnNewTxsFunc == nil {
+		var (
+			errOut error
+		)
+		return errOut
+	}
+	return mock.OnNewTxsFunc(newTxs)
+}
+
+// OnNewTxsCalls gets all the calls that were made to OnNewTxs.
+// Check the length with:
+//     len(mockedPool.OnNewTxsCalls())
+func (mock *PoolMock) OnNewTxsCalls() []struct {
+	NewTxs TxSlots
+} {
+	var calls []struct {
+		NewTxs TxSlots
+	}
+	mock.lockOnNewTxs.RLock()
+	calls = mock.calls.OnNewTxs
+	mock.lockOnNewTxs.RUnlock()
 	return calls
 }
@@ -171,3 +171,103 @@ func EncodePooledTransactions65(txsRlp [][]byte, encodeBuf []byte) []byte {
 	_ = pos
 	return encodeBuf
 }
+
+// EncodeTransactions produces encoding of TRANSACTIONS_65 and TRANSACTIONS_66 packets (they are the same):
+// RLP list of transactions, txsRlp must be encoded as in the network (typed transactions wrapped into RLP string)
+func EncodeTransactions(txsRlp [][]byte, encodeBuf []byte) []byte {
+	pos := 0
+	dataLen := 0
+	for i := range txsRlp {
+		dataLen += len(txsRlp[i])
+	}
+
+	encodeBuf = ensureEnoughSize(encodeBuf, rlp.ListPrefixLen(dataLen)+dataLen)
+	// Length Prefix for the entire structure
+	pos += rlp.EncodeListPrefix(dataLen, encodeBuf[pos:])
+	for i := range txsRlp {
+		copy(encodeBuf[pos:], txsRlp[i])
+		pos += len(txsRlp[i])
+	}
+	_ = pos
+	return encodeBuf
+}
+
+// ParseTransactions parses RLP list of transactions - payload of TRANSACTIONS_65, TRANSACTIONS_66 and
+// POOLED_TRANSACTIONS_65 messages, and appends parsed transactions to txSlots (as remote transactions).
+// Transactions which fail to parse are skipped and their errors are returned in parseErrs, without dropping
+// the rest of the list. Returned err is not nil only if the list itself is malformed
+func ParseTransactions(payload []byte, pos int, ctx *TxParseContext, txSlots *TxSlots) (newPos int, parseErrs []error, err error) {
+	txsRlp, newPos, err := splitTransactions(payload, pos)
+	if err != nil {
+		return 0, nil, err
+	}
+	for i := range txsRlp {
+		slot, sender, _, err := ctx.ParseTransaction(txsRlp[i], 0)
+		if err != nil {
+			parseErrs = append(parseErrs, fmt.Errorf("transaction %d: %w", i, err))
+		} else {
+			txSlots.Append(slot, sender[:], false)
+		}
+	}
+	return newPos, parseErrs, nil
+}
+
+// splitTransactions - returns RLP of every transaction of RLP list, without parsing them. Element boundaries are
+// known before parsing, so one broken transaction doesn't affect the others
+func splitTransactions(payload []byte, pos int) (txsRlp [][]byte, newPos int, err error) {
+	if pos >= len(payload) {
+		return nil, 0, fmt.Errorf("%s: empty transactions list", ParseTransactionErrorPrefix)
+	}
+	dataPos, dataLen, err := rlp.List(payload, pos)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%s: transactions len: %w", ParseTransactionErrorPrefix, err)
+	}
+	end := dataPos + dataLen
+	if end > len(payload) {
+		return nil, 0, fmt.Errorf("%s: unexpected end of transactions list", ParseTransactionErrorPrefix)
+	}
+	for i, txPos := 0, dataPos; txPos < end; i++ {
+		elemPos, elemLen, _, err := rlp.Prefix(payload, txPos)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%s: transaction %d len: %w", ParseTransactionErrorPrefix, i, err)
+		}
+		elemEnd := elemPos + elemLen
+		if elemEnd > end {
+			return nil, 0, fmt.Errorf("%s: transaction %d exceeds the list", ParseTransactionErrorPrefix, i)
+		}
+		txsRlp = append(txsRlp, payload[txPos:elemEnd])
+		txPos = elemEnd
+	}
+	return txsRlp, end, nil
+}
+
+// ParsePooledTransactions66 parses payload of POOLED_TRANSACTIONS_66 message - request id followed by
+// the list of transactions. See ParseTransactions for the handling of individual transactions
+func ParsePooledTransactions66(payload []byte, pos int, ctx *TxParseContext, txSlots *TxSlots) (requestID uint64, newPos int, parseErrs []error, err error) {
+	requestID, pos, err = parseRequestID66(payload, pos)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+	newPos, parseErrs, err = ParseTransactions(payload, pos, ctx, txSlots)
+	if err != nil {
+		return 0, 0, parseErrs, err
+	}
+	return requestID, newPos, parseErrs, nil
+}
+
+// parseRequestID66 - parses beginning of eth/66 packet: list prefix and request id, returns position of the
+// packet's payload
+func parseRequestID66(payload []byte, pos int) (requestID uint64, newPos int, err error) {
+	if pos >= len(payload) {
+		return 0, 0, fmt.Errorf("%s: empty pooled transactions packet", ParseTransactionErrorPrefix)
+	}
+	pos, _, err = rlp.List(payload, pos)
+	if err != nil {
+		return 0, 0, err
+	}
+	if pos >= len(payload) {
+		return 0, 0, fmt.Errorf("%s: unexpected end of pooled transactions packet", ParseTransactionErrorPrefix)
+	}
+	newPos, requestID, err = rlp.U64(payload, pos)
+	return requestID, newPos, err
+}
@@ -136,3 +136,61 @@ func TestPooledTransactionsPacket66(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeTransactions(t *testing.T) {
+	for i, tt := range ptp66EncodeTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			// same list of transactions as in POOLED_TRANSACTIONS_66, but without request id
+			encoded := EncodeTransactions(tt.txs, nil)
+			require.Equal(t, tt.encoded[len("f8d7820457"):], fmt.Sprintf("%x", encoded))
+		})
+	}
+}
+
+func TestParsePooledTransactions(t *testing.T) {
+	var txsRlp [][]byte
+	for _, tt := range txParseTests {
+		txsRlp = append(txsRlp, decodeHex(tt.payloadStr))
+	}
+	// truncated legacy transaction in the middle of the list must not affect the others
+	txsRlp = append(txsRlp[:2:2], append([][]byte{decodeHex("c3010203")}, txsRlp[2:]...)...)
+	check := func(t *testing.T, txs TxSlots, parseErrs []error) {
+		require := require.New(t)
+		require.Len(parseErrs, 1)
+		require.Equal(len(txParseTests), len(txs.txs))
+		require.Equal(20*len(txParseTests), len(txs.senders))
+		for i, tt := range txParseTests {
+			require.Equal(decodeHex(tt.payloadStr), txs.txs[i].rlp)
+			require.Equal(decodeHex(tt.idHashStr), txs.txs[i].idHash[:])
+			if tt.senderStr != "" {
+				require.Equal(decodeHex(tt.senderStr), txs.senders[i*20:(i+1)*20])
+			}
+			require.False(txs.isLocal[i])
+		}
+	}
+	ctx := NewTxParseContext()
+	t.Run("65", func(t *testing.T) {
+		payload := EncodePooledTransactions65(txsRlp, nil)
+		var txs TxSlots
+		newPos, parseErrs, err := ParseTransactions(payload, 0, ctx, &txs)
+		require.NoError(t, err)
+		require.Equal(t, len(payload), newPos)
+		check(t, txs, parseErrs)
+	})
+	t.Run("66", func(t *testing.T) {
+		payload := EncodePooledTransactions66(txsRlp, 1111, nil)
+		var txs TxSlots
+		requestID, newPos, parseErrs, err := ParsePooledTransactions66(payload, 0, ctx, &txs)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1111), requestID)
+		require.Equal(t, len(payload), newPos)
+		check(t, txs, parseErrs)
+	})
+	t.Run("malformed list", func(t *testing.T) {
+		var txs TxSlots
+		_, _, err := ParseTransactions(decodeHex("f8"), 0, ctx, &txs)
+		require.Error(t, err)
+		_, _, _, err = ParsePooledTransactions66(decodeHex("c0"), 0, ctx, &txs)
+		require.Error(t, err)
+	})
+}
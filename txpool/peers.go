@@ -0,0 +1,118 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// maxKnownTxs - limit of remembered hashes per peer, same as in geth
+const maxKnownTxs = 32768
+
+// Peers - connected peers and hashes of transactions which every peer knows: announced or sent them to us, or
+// received them from us. Fetch adds peers and hashes they announce, Send skips peers which already know a hash.
+// Same object must be passed to Fetch and Send
+type Peers struct {
+	lock  sync.RWMutex
+	peers map[string]*peerInfo // peerKey => peer
+}
+
+type peerInfo struct {
+	id     PeerID
+	sentry SentryClient // sentry the peer is connected to
+	known  *lru.Cache   // [32]byte => struct{}
+}
+
+func NewPeers() *Peers {
+	return &Peers{peers: map[string]*peerInfo{}}
+}
+
+// Add - remembers connected peer, does nothing if it's already known
+func (p *Peers) Add(id PeerID, sentryClient SentryClient) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	key := peerKey(id)
+	if _, ok := p.peers[key]; ok {
+		return
+	}
+	known, _ := lru.New(maxKnownTxs)
+	p.peers[key] = &peerInfo{id: id, sentry: sentryClient, known: known}
+}
+
+// Remove - forgets disconnected peer with its known hashes
+func (p *Peers) Remove(id PeerID) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	delete(p.peers, peerKey(id))
+}
+
+func (p *Peers) Len() int {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return len(p.peers)
+}
+
+// MarkKnown - peer knows transactions with given hashes. Unknown peers are ignored
+func (p *Peers) MarkKnown(id PeerID, hashes Hashes) {
+	p.lock.RLock()
+	peer, ok := p.peers[peerKey(id)]
+	p.lock.RUnlock()
+	if !ok {
+		return
+	}
+	peer.markKnown(hashes)
+}
+
+func (peer *peerInfo) markKnown(hashes Hashes) {
+	for i := 0; i < hashes.Len(); i++ {
+		var hash [32]byte
+		copy(hash[:], hashes.At(i))
+		peer.known.Add(hash, struct{}{})
+	}
+}
+
+// unknown - returns hashes which peer doesn't know
+func (peer *peerInfo) unknown(hashes Hashes) (unknown Hashes) {
+	for i := 0; i < hashes.Len(); i++ {
+		var hash [32]byte
+		copy(hash[:], hashes.At(i))
+		if !peer.known.Contains(hash) {
+			unknown = append(unknown, hash[:]...)
+		}
+	}
+	return unknown
+}
+
+// get - returns peer, nil if it's unknown
+func (p *Peers) get(id PeerID) *peerInfo {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.peers[peerKey(id)]
+}
+
+// all - snapshot of connected peers
+func (p *Peers) all() []*peerInfo {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	peers := make([]*peerInfo, 0, len(p.peers))
+	for _, peer := range p.peers {
+		peers = append(peers, peer)
+	}
+	return peers
+}
@@ -20,12 +20,16 @@ import (
 	"container/heap"
 	"context"
 	"fmt"
+	"math"
 	"sync"
 	"time"
 
 	"github.com/google/btree"
 	lru "github.com/hashicorp/golang-lru"
 	"github.com/holiman/uint256"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
 	"go.uber.org/atomic"
 )
 
@@ -36,10 +40,14 @@ type Pool interface {
 	// IdHashKnown check whether transaction with given Id hash is known to the pool
 	IdHashKnown(hash []byte) bool
 	GetRlp(hash []byte) []byte
+	// OnNewTxs adds batch of transactions received from the network
+	OnNewTxs(newTxs TxSlots) error
 
-	NotifyNewPeer(peerID PeerID)
+	OnNewPeer(peerID PeerID)
 }
 
+var _ Pool = &TxPool{} // compile-time interface check
+
 // SubPoolMarker ordered bitset responsible to sort transactions by sub-pools. Bits meaning:
 // 1. Minimum fee requirement. Set to 1 if feeCap of the transaction is no less than in-protocol parameter of minimal base fee. Set to 0 if feeCap is less than minimum base fee, which means this transaction will never be included into this particular chain.
 // 2. Absence of nonce gaps. Set to 1 for transactions whose nonce is N, state nonce for the sender is M, and there are transactions for all nonces between M and N from the same sender. Set to 0 is the transaction's nonce is divided from the state nonce by one or more nonce gaps.
@@ -56,6 +64,25 @@ const (
 	IsLocal              = 0b00001
 )
 
+// SubPoolMarkerBits - SubPoolMarker decoded bit by bit, for introspection
+type SubPoolMarkerBits struct {
+	EnoughFeeCapProtocol bool
+	NoNonceGaps          bool
+	EnoughBalance        bool
+	EnoughFeeCapBlock    bool
+	IsLocal              bool
+}
+
+func (m SubPoolMarker) Bits() SubPoolMarkerBits {
+	return SubPoolMarkerBits{
+		EnoughFeeCapProtocol: m&EnoughFeeCapProtocol != 0,
+		NoNonceGaps:          m&NoNonceGaps != 0,
+		EnoughBalance:        m&EnoughBalance != 0,
+		EnoughFeeCapBlock:    m&EnoughFeeCapBlock != 0,
+		IsLocal:              m&IsLocal != 0,
+	}
+}
+
 // MetaTx holds transaction and some metadata
 type MetaTx struct {
 	SubPool                SubPoolMarker // Aggregated field
@@ -65,10 +92,30 @@ type MetaTx struct {
 	bestIndex              int
 	worstIndex             int
 	currentSubPool         SubPoolType
+
+	// minTip, minFeeCap - minimum over transactions of the same sender with nonce up to this one: this
+	// transaction can't be included earlier than them, so it can't be more attractive than them.
+	// effectiveTip - min(minTip, minFeeCap - blockBaseFee) if minFeeCap is enough for blockBaseFee, zero otherwise.
+	// Depends on blockBaseFee only when fee cap limits the tip, see onBaseFeeChange
+	minTip       uint64
+	minFeeCap    uint64
+	effectiveTip uint64
+
+	// rebroadcastAt, rebroadcastInterval - when local transaction is re-announced next time and backoff after
+	// that, see localsToRebroadcast. Zero - not scheduled yet
+	rebroadcastAt       time.Time
+	rebroadcastInterval time.Duration
+
+	// private - local transaction which is never sent to peers, see TxSlots.AppendPrivate. privateSince - pending
+	// block height when it was added to the pool, for TxPoolConfig.PrivateTxBlocks
+	private      bool
+	privateSince uint64
+
+	timestamp time.Time // when transaction arrived to the pool (or the pool was restarted), see evictExpired
 }
 
-func newMetaTx(slot *TxSlot, isLocal bool) *MetaTx {
-	mt := &MetaTx{Tx: slot, worstIndex: -1, bestIndex: -1}
+func newMetaTx(slot *TxSlot, isLocal, private bool) *MetaTx {
+	mt := &MetaTx{Tx: slot, worstIndex: -1, bestIndex: -1, private: private, timestamp: time.Now()}
 	if isLocal {
 		mt.SubPool = IsLocal
 	}
@@ -81,9 +128,162 @@ const PendingSubPool SubPoolType = 1
 const BaseFeeSubPool SubPoolType = 2
 const QueuedSubPool SubPoolType = 3
 
-const PendingSubPoolLimit = 1024
-const BaseFeeSubPoolLimit = 1024
-const QueuedSubPoolLimit = 1024
+func (t SubPoolType) String() string {
+	switch t {
+	case PendingSubPool:
+		return "pending"
+	case BaseFeeSubPool:
+		return "baseFee"
+	case QueuedSubPool:
+		return "queued"
+	default:
+		return fmt.Sprintf("unknown sub-pool: %d", uint8(t))
+	}
+}
+
+// DiscardReason - outcome of adding transaction to the pool, or reason of its removal from the pool
+type DiscardReason uint8
+
+const (
+	NotSet              DiscardReason = 0 // analog of "nil-value", means it will be set in future
+	Success             DiscardReason = 1
+	AlreadyKnown        DiscardReason = 2
+	Mined               DiscardReason = 3
+	ReplaceUnderpriced  DiscardReason = 4 // transaction with same sender and nonce is in the pool, and this one doesn't pay TxPoolConfig.PriceBump more
+	FeeTooLow           DiscardReason = 5 // feeCap is less than in-protocol minimal base fee
+	NonceTooLow         DiscardReason = 6 // nonce is less than nonce of the sender in the state
+	PendingPoolOverflow DiscardReason = 7
+	BaseFeePoolOverflow DiscardReason = 8
+	QueuedPoolOverflow  DiscardReason = 9
+	Replaced            DiscardReason = 10 // replaced by transaction with same sender and nonce, but higher fees
+	OversizedData       DiscardReason = 11 // encoding is bigger than TxPoolConfig.MaxTxSize
+	IntrinsicGas        DiscardReason = 12 // gas is not enough even to start execution
+	GasLimitTooHigh     DiscardReason = 13 // gas is bigger than TxPoolConfig.BlockGasLimit, transaction doesn't fit into any block
+	TipAboveFeeCap      DiscardReason = 14
+	SenderPoolOverflow  DiscardReason = 15 // sender has TxPoolConfig.AccountSlots of non-local transactions in the pool
+	TxTypeNotSupported  DiscardReason = 16 // fork which introduced type of the transaction is not activated at pending block
+	PolicyRejected      DiscardReason = 17 // rejected by AdmissionPolicy
+	PrivateExpired      DiscardReason = 18 // private transaction wasn't mined within TxPoolConfig.PrivateTxBlocks
+	LifetimeExpired     DiscardReason = 19 // non-local transaction is still not pending after TxPoolConfig.QueuedLifetime
+)
+
+func (r DiscardReason) String() string {
+	switch r {
+	case NotSet:
+		return "not set"
+	case Success:
+		return "success"
+	case AlreadyKnown:
+		return "already known"
+	case Mined:
+		return "mined"
+	case ReplaceUnderpriced:
+		return "replacement transaction underpriced"
+	case FeeTooLow:
+		return "fee too low"
+	case NonceTooLow:
+		return "nonce too low"
+	case PendingPoolOverflow:
+		return "pending sub-pool is full"
+	case BaseFeePoolOverflow:
+		return "baseFee sub-pool is full"
+	case QueuedPoolOverflow:
+		return "queued sub-pool is full"
+	case Replaced:
+		return "replaced"
+	case OversizedData:
+		return "oversized data"
+	case IntrinsicGas:
+		return "intrinsic gas too low"
+	case GasLimitTooHigh:
+		return "exceeds block gas limit"
+	case TipAboveFeeCap:
+		return "max priority fee per gas higher than max fee per gas"
+	case SenderPoolOverflow:
+		return "too many transactions of the sender"
+	case TxTypeNotSupported:
+		return "transaction type not supported"
+	case PolicyRejected:
+		return "rejected by admission policy"
+	case PrivateExpired:
+		return "private transaction expired"
+	case LifetimeExpired:
+		return "transaction lifetime expired"
+	default:
+		return fmt.Sprintf("unknown discard reason: %d", uint8(r))
+	}
+}
+
+// txSlotSize - transactions occupy space in sub-pools by slots: one slot for every started 32KB of RLP, so
+// transactions with big calldata can't take a lot of memory by one slot
+const txSlotSize = 32 * 1024
+
+func txSlots(tx *TxSlot) int {
+	if len(tx.rlp) <= txSlotSize {
+		return 1
+	}
+	return (len(tx.rlp) + txSlotSize - 1) / txSlotSize
+}
+
+// TxPoolConfig - settings of TxPool
+type TxPoolConfig struct {
+	// PriceBump - minimum increase (in percents) of both tip and feeCap, which transaction must offer to replace
+	// pooled transaction with the same sender and nonce. Protects from cheap churn of the pool and the network
+	PriceBump uint64
+	// ChainID - transactions signed for other chains are rejected. Zero - not checked
+	ChainID uint256.Int
+	// MaxTxSize - limit of RLP size of one transaction, protects from DoS by big transactions
+	MaxTxSize uint64
+	// BlockGasLimit - transactions which need more gas can't be included into a block
+	BlockGasLimit uint64
+	// Chain - transaction types are accepted only after forks which introduced them, see ChainConfig
+	Chain ChainConfig
+	// Admission - which transactions may enter the pool, nil admits all. See TxPool.SetAdmissionPolicy
+	Admission AdmissionPolicy
+	// PrivateTxBlocks - private transactions (see TxSlots.AppendPrivate) which aren't mined within this number of
+	// blocks after they are added to the pool (or the pool is restarted) are dropped, or become ordinary local
+	// transactions and are announced to peers if PublishExpiredPrivate is set. Zero - never expire
+	PrivateTxBlocks       uint64
+	PublishExpiredPrivate bool
+	// ProtocolBaseFee - in-protocol minimal base fee, transactions with lower feeCap can never be mined. Used by
+	// BlockStream, which doesn't receive it with blocks
+	ProtocolBaseFee uint64
+	// QueuedLifetime - non-local transactions of queued and baseFee sub-pools are evicted this time after they
+	// arrived, otherwise transaction behind nonce gap which is never filled stays until sub-pool overflows.
+	// Zero - never expire
+	QueuedLifetime time.Duration
+
+	// Limits of sub-pools and of transactions of one sender, in slots (see txSlotSize). Local transactions
+	// don't occupy slots: they are never evicted when sub-pool overflows
+	PendingSubPoolLimit int
+	BaseFeeSubPoolLimit int
+	QueuedSubPoolLimit  int
+	AccountSlots        int
+
+	// SenderStateLoaders - how many senders may have their state loaded at once. WaitingTxsLimit - how many
+	// transactions may wait for state of their senders, next ones are dropped until some state is loaded
+	SenderStateLoaders int
+	WaitingTxsLimit    int
+
+	rules ChainRules // derived from Chain by New
+}
+
+var DefaultConfig = TxPoolConfig{
+	PriceBump:     10,         // same as geth
+	MaxTxSize:     128 * 1024, // same as geth
+	BlockGasLimit: 30_000_000,
+	// EIP-1559 can't decrease base fee below this value: decrease is 1/8 of base fee at most, and rounded down
+	ProtocolBaseFee: 7,
+
+	PendingSubPoolLimit: 1024,
+	BaseFeeSubPoolLimit: 1024,
+	QueuedSubPoolLimit:  1024,
+	AccountSlots:        16,            // same as geth
+	QueuedLifetime:      3 * time.Hour, // same as geth
+
+	SenderStateLoaders: 16,
+	WaitingTxsLimit:    4096,
+}
 
 type nonce2Tx struct{ *btree.BTree }
 
@@ -108,83 +308,152 @@ func (i *nonce2TxItem) Less(than btree.Item) bool {
 // most of logic implemented by pure tests-friendly functions
 type TxPool struct {
 	lock *sync.RWMutex
+	cfg  TxPoolConfig
 
 	protocolBaseFee atomic.Uint64
 	blockBaseFee    atomic.Uint64
+	blockHeight     atomic.Uint64 // of the last applied block, transactions are validated for the next one
 
-	senderIDs                map[string]uint64
+	senderIDs                *sendersRegistry
 	senderInfo               map[uint64]*senderInfo
-	byHash                   map[string]*MetaTx // tx_hash => tx
+	gcCandidates             map[uint64]struct{} // senders which may have no transactions left, see gcSenders
+	byHash                   map[string]*MetaTx  // tx_hash => tx
 	pending, baseFee, queued *SubPool
 
-	// track isLocal flag of already mined transactions. used at unwind.
+	// state of unknown senders is loaded asynchronously, their transactions wait here until it arrives
+	senderState    SenderStateProvider
+	waitingSenders map[uint64]*TxSlots // senderID => transactions held back
+	waitingHashes  map[string]struct{} // idHash of transactions held back
+	loadQueue      []uint64            // senders which wait for a free loader, see TxPoolConfig.SenderStateLoaders
+	loaders        int                 // running loads of sender state
+
+	// track isLocal flag of already mined transactions. used at unwind. Values are senderIDs
 	localsHistory *lru.Cache
 
+	// persistence of the pool: what is already written to the db (nil - unknown) and last block seen by the pool
+	persisted        *persistedState
+	lastSeenBlock    [32]byte
+	hasLastSeenBlock bool
+
 	// fields for transaction propagation
 	recentlyConnectedPeers *recentlyConnectedPeers
 	newTxs                 chan Hashes
+	subscribers            *subscribers // see Subscribe
 	//lastTxPropagationTimestamp time.Time
 }
 
-func New(newTxs chan Hashes) *TxPool {
-	localsHistory, _ := lru.New(1024)
-	return &TxPool{
+// New creates transaction pool. senderState is used to load nonce and balance of senders which are not
+// known to the pool yet. If it's nil - transactions of unknown senders are dropped
+func New(newTxs chan Hashes, cfg TxPoolConfig, senderState SenderStateProvider) (*TxPool, error) {
+	var err error
+	if cfg.rules, err = cfg.Chain.Rules(); err != nil {
+		return nil, err
+	}
+	p := &TxPool{
 		lock:                   &sync.RWMutex{},
+		cfg:                    cfg,
+		senderIDs:              newSendersRegistry(),
 		senderInfo:             map[uint64]*senderInfo{},
+		gcCandidates:           map[uint64]struct{}{},
+		senderState:            senderState,
+		waitingSenders:         map[uint64]*TxSlots{},
+		waitingHashes:          map[string]struct{}{},
 		byHash:                 map[string]*MetaTx{},
-		localsHistory:          localsHistory,
 		recentlyConnectedPeers: &recentlyConnectedPeers{},
 		pending:                NewSubPool(),
 		baseFee:                NewSubPool(),
 		queued:                 NewSubPool(),
 		newTxs:                 newTxs,
+		subscribers:            newSubscribers(),
 	}
+	// history is modified under lock of the pool
+	p.localsHistory, _ = lru.NewWithEvict(1024, func(_, senderID interface{}) {
+		if id, ok := senderID.(uint64); ok {
+			p.gcCandidates[id] = struct{}{}
+		}
+	})
+	return p, nil
 }
 
+// GetRlp - RLP of transaction which can be sent to peers: nil if it's unknown or private
 func (p *TxPool) GetRlp(hash []byte) []byte {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
+	txn, ok := p.byHash[string(hash)]
+	if !ok || txn.private {
+		return nil
+	}
+	return txn.Tx.rlp
+}
+
+// GetRlpWithPrivate - RLP of transaction, including private ones. Not for p2p: for local consumers, like RPC or
+// own block builder
+func (p *TxPool) GetRlpWithPrivate(hash []byte) []byte {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
 	txn, ok := p.byHash[string(hash)]
 	if !ok {
 		return nil
 	}
 	return txn.Tx.rlp
 }
-func (p *TxPool) AppendLocalHashes(buf []byte) {
+
+// AppendLocalHashes - appends hashes of local transactions (except private ones) to buf, returns extended buf
+func (p *TxPool) AppendLocalHashes(buf []byte) []byte {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
-	i := 0
 	for hash, txn := range p.byHash {
-		if txn.SubPool&IsLocal == 0 {
+		if txn.SubPool&IsLocal == 0 || txn.private {
 			continue
 		}
-		copy(buf[i*32:(i+1)*32], hash)
-		i++
+		buf = append(buf, hash...)
 	}
+	return buf
 }
-func (p *TxPool) AppendRemoteHashes(buf []byte) {
+
+// AppendRemoteHashes - appends hashes of remote transactions to buf, returns extended buf
+func (p *TxPool) AppendRemoteHashes(buf []byte) []byte {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	i := 0
 	for hash, txn := range p.byHash {
 		if txn.SubPool&IsLocal != 0 {
 			continue
 		}
-		copy(buf[i*32:(i+1)*32], hash)
-		i++
+		buf = append(buf, hash...)
 	}
+	return buf
 }
-func (p *TxPool) AppendAllHashes(buf []byte) {
-	p.AppendLocalHashes(buf)
-	p.AppendRemoteHashes(buf[len(buf):])
+
+// AppendAllHashes - appends hashes of all transactions, which may be sent to peers, to buf, returns extended buf
+func (p *TxPool) AppendAllHashes(buf []byte) []byte {
+	buf = p.AppendLocalHashes(buf)
+	return p.AppendRemoteHashes(buf)
 }
+
+// ForEach - iterates over all transactions of the pool, sub-pool by sub-pool. sender is 20-byte address
+func (p *TxPool) ForEach(f func(rlp, sender []byte, t SubPoolType)) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	for _, sub := range []*SubPool{p.pending, p.baseFee, p.queued} {
+		for _, mt := range *sub.best {
+			f(mt.Tx.rlp, p.senderIDs.addr(mt.Tx.senderID), mt.currentSubPool)
+		}
+	}
+}
+
+// IdHashKnown - transaction is in the pool, or waits for state of its sender
 func (p *TxPool) IdHashKnown(hash []byte) bool {
 	p.lock.RLock()
 	defer p.lock.RUnlock()
 
-	_, ok := p.byHash[string(hash)]
+	if _, ok := p.byHash[string(hash)]; ok {
+		return true
+	}
+	_, ok := p.waitingHashes[string(hash)]
 	return ok
 }
 func (p *TxPool) IdHashIsLocal(hash []byte) bool {
@@ -202,20 +471,79 @@ func (p *TxPool) OnNewPeer(peerID PeerID) { p.recentlyConnectedPeers.AddPeer(pee
 func (p *TxPool) OnNewTxs(newTxs TxSlots) error {
 	p.lock.Lock()
 	defer p.lock.Unlock()
-	protocolBaseFee, blockBaseFee := p.protocolBaseFee.Load(), p.blockBaseFee.Load()
-	if protocolBaseFee == 0 || blockBaseFee == 0 {
-		return fmt.Errorf("non-zero base fee")
+	setTxSenderID(p.senderIDs, newTxs)
+	_, err := p.addTxsLocked(p.holdUnknownSenders(newTxs))
+	return err
+}
+
+// AddLocals - adds transactions submitted to this node (for example by RPC), which must be marked as local.
+// Unlike OnNewTxs, it loads state of unknown senders synchronously - so outcome of every transaction
+// is known when it returns
+func (p *TxPool) AddLocals(ctx context.Context, newTxs TxSlots) ([]DiscardReason, error) {
+	p.lock.Lock()
+	unknown := map[[20]byte]struct{}{}
+	for i := range newTxs.txs {
+		addr := newTxs.senders[i*20 : (i+1)*20]
+		if id, ok := p.senderIDs.id(string(addr)); ok {
+			if _, ok = p.senderInfo[id]; ok {
+				continue
+			}
+		}
+		var unknownAddr [20]byte
+		copy(unknownAddr[:], addr)
+		unknown[unknownAddr] = struct{}{}
 	}
+	p.lock.Unlock()
 
-	setTxSenderID(p.senderIDs, p.senderInfo, newTxs)
-	if err := onNewTxs(p.senderInfo, newTxs, protocolBaseFee, blockBaseFee, p.pending, p.baseFee, p.queued, p.byHash, p.localsHistory); err != nil {
-		return err
+	if len(unknown) > 0 && p.senderState == nil {
+		return nil, fmt.Errorf("state of %d senders is unknown", len(unknown))
+	}
+	loaded := make(map[[20]byte]*senderInfo, len(unknown))
+	for addr := range unknown {
+		nonce, balance, err := p.senderState.SenderState(ctx, addr)
+		if err != nil {
+			return nil, fmt.Errorf("loading sender state: %w", err)
+		}
+		loaded[addr] = newSenderInfo(nonce, balance)
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	// ids are assigned only now: senders could be forgotten while the lock was released
+	setTxSenderID(p.senderIDs, newTxs)
+	for addr, info := range loaded {
+		senderID := p.senderIDs.getOrCreateID(string(addr[:]))
+		if _, ok := p.senderInfo[senderID]; !ok {
+			p.senderInfo[senderID] = info
+		}
+	}
+	return p.addTxsLocked(newTxs)
+}
+
+// addTxsLocked - adds transactions of known senders to the pool and notifies about them. Must be called under lock
+func (p *TxPool) addTxsLocked(newTxs TxSlots) ([]DiscardReason, error) {
+	if len(newTxs.txs) == 0 {
+		return nil, nil
+	}
+	// senders of rejected transactions may have no others
+	for _, tx := range newTxs.txs {
+		p.gcCandidates[tx.senderID] = struct{}{}
+	}
+	protocolBaseFee, blockBaseFee, pendingHeight := p.protocolBaseFee.Load(), p.blockBaseFee.Load(), p.blockHeight.Load()+1
+	// blocks before London have no base fee
+	if protocolBaseFee == 0 || (blockBaseFee == 0 && p.cfg.rules.IsLondon(pendingHeight)) {
+		return nil, fmt.Errorf("non-zero base fee")
+	}
+
+	reasons, err := onNewTxs(p.cfg, p.senderInfo, newTxs, protocolBaseFee, blockBaseFee, pendingHeight, p.pending, p.baseFee, p.queued, p.byHash, p.localsHistory, p.gcCandidates, p.onEvent)
+	if err != nil {
+		return nil, err
 	}
 
 	notifyNewTxs := make(Hashes, 0, 32*len(newTxs.txs))
 	for i := range newTxs.txs {
-		_, ok := p.byHash[string(newTxs.txs[i].idHash[:])]
-		if !ok {
+		mt, ok := p.byHash[string(newTxs.txs[i].idHash[:])]
+		if !ok || mt.private {
 			continue
 		}
 		notifyNewTxs = append(notifyNewTxs, newTxs.txs[i].idHash[:]...)
@@ -227,59 +555,268 @@ func (p *TxPool) OnNewTxs(newTxs TxSlots) error {
 		}
 	}
 
-	return nil
+	return reasons, nil
 }
-func onNewTxs(senderInfo map[uint64]*senderInfo, newTxs TxSlots, protocolBaseFee, blockBaseFee uint64, pending, baseFee, queued *SubPool, byHash map[string]*MetaTx, localsHistory *lru.Cache) error {
+
+// holdUnknownSenders - returns transactions whose senders state is known, and holds back the rest
+// until state of their senders is loaded by senderState. Held transactions which are received again are
+// skipped, transactions over TxPoolConfig.WaitingTxsLimit are dropped. Must be called under lock
+func (p *TxPool) holdUnknownSenders(txs TxSlots) (known TxSlots) {
+	for i, tx := range txs.txs {
+		if _, ok := p.senderInfo[tx.senderID]; ok {
+			known.appendFrom(&txs, i)
+			continue
+		}
+		if _, ok := p.waitingHashes[string(tx.idHash[:])]; ok {
+			continue
+		}
+		if p.senderState == nil || len(p.waitingHashes) >= p.cfg.WaitingTxsLimit {
+			p.gcCandidates[tx.senderID] = struct{}{}
+			continue
+		}
+		waiting, ok := p.waitingSenders[tx.senderID]
+		if !ok {
+			waiting = &TxSlots{}
+			p.waitingSenders[tx.senderID] = waiting
+			p.loadQueue = append(p.loadQueue, tx.senderID)
+		}
+		waiting.appendFrom(&txs, i)
+		p.waitingHashes[string(tx.idHash[:])] = struct{}{}
+	}
+	p.startLoaders()
+	return known
+}
+
+// startLoaders - starts loading state of senders from loadQueue, while there are less than
+// TxPoolConfig.SenderStateLoaders running loads. Must be called under lock
+func (p *TxPool) startLoaders() {
+	for p.loaders < p.cfg.SenderStateLoaders && len(p.loadQueue) > 0 {
+		senderID := p.loadQueue[0]
+		p.loadQueue = p.loadQueue[1:]
+		waiting, ok := p.waitingSenders[senderID]
+		if !ok {
+			continue
+		}
+		var addr [20]byte
+		copy(addr[:], waiting.senders[:20])
+		p.loaders++
+		go p.loadSenderState(senderID, addr, waiting)
+	}
+}
+
+// loadSenderState - loads state of the sender by senderState and admits transactions held back for it
+func (p *TxPool) loadSenderState(senderID uint64, addr [20]byte, waiting *TxSlots) {
+	ctx, cancel := context.WithTimeout(context.Background(), senderStateLoadTimeout)
+	defer cancel()
+	nonce, balance, err := p.senderState.SenderState(ctx, addr)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.loaders--
+	defer p.startLoaders()
+	if p.waitingSenders[senderID] != waiting {
+		// pool was resynced while loading, loaded state may be outdated - new load is already queued
+		return
+	}
+	delete(p.waitingSenders, senderID)
+	for _, tx := range waiting.txs {
+		delete(p.waitingHashes, string(tx.idHash[:]))
+	}
+	if err != nil {
+		// held transactions are dropped - they will be received again with next announcements
+		p.gcCandidates[senderID] = struct{}{}
+		log.Warn("[txpool] loading sender state", "sender", fmt.Sprintf("%x", addr), "err", err)
+		return
+	}
+	if _, ok := p.senderInfo[senderID]; !ok {
+		p.senderInfo[senderID] = newSenderInfo(nonce, balance)
+	}
+	if _, err := p.addTxsLocked(*waiting); err != nil {
+		log.Warn("[txpool] adding transactions of loaded sender", "sender", fmt.Sprintf("%x", addr), "err", err)
+	}
+}
+
+// ResetSenders - forgets state of all senders and re-imports all transactions of the pool, so state of their
+// senders is requested from senderState again. Used when the pool missed some state changes (for example,
+// gap in the stream of blocks) and can't trust its state anymore
+func (p *TxPool) ResetSenders() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var txs TxSlots
+	for _, sub := range []*SubPool{p.pending, p.baseFee, p.queued} {
+		for _, mt := range *sub.best {
+			if mt.private {
+				txs.AppendPrivate(mt.Tx, p.senderIDs.addr(mt.Tx.senderID))
+				continue
+			}
+			txs.Append(mt.Tx, p.senderIDs.addr(mt.Tx.senderID), mt.SubPool&IsLocal != 0)
+		}
+	}
+	for _, waiting := range p.waitingSenders {
+		for i := range waiting.txs {
+			txs.appendFrom(waiting, i)
+		}
+	}
+
+	p.senderInfo = map[uint64]*senderInfo{}
+	p.waitingSenders, p.waitingHashes, p.loadQueue = map[uint64]*TxSlots{}, map[string]struct{}{}, nil
+	p.byHash = map[string]*MetaTx{}
+	p.pending, p.baseFee, p.queued = NewSubPool(), NewSubPool(), NewSubPool()
+	_, err := p.addTxsLocked(p.holdUnknownSenders(txs))
+	return err
+}
+
+// onNewTxs - transactions are validated for block pendingHeight. emit receives every change of transactions in
+// the pool, can be nil
+func onNewTxs(cfg TxPoolConfig, senderInfo map[uint64]*senderInfo, newTxs TxSlots, protocolBaseFee, blockBaseFee, pendingHeight uint64, pending, baseFee, queued *SubPool, byHash map[string]*MetaTx, localsHistory *lru.Cache, gcCandidates map[uint64]struct{}, emit eventFunc) ([]DiscardReason, error) {
+	if emit == nil {
+		emit = noEvents
+	}
 	for i := range newTxs.txs {
 		if newTxs.txs[i].senderID == 0 {
-			return fmt.Errorf("senderID can't be zero")
+			return nil, fmt.Errorf("senderID can't be zero")
+		}
+	}
+
+	reasons := make([]DiscardReason, len(newTxs.txs))
+	var validTxs TxSlots
+	var validIdx []int // position in newTxs of every transaction of validTxs
+	for i, tx := range newTxs.txs {
+		if reasons[i] = validateTx(cfg, tx, pendingHeight); reasons[i] != NotSet {
+			emit(&MetaTx{Tx: tx}, TxRejected, 0, reasons[i])
+			continue
+		}
+		if cfg.Admission != nil {
+			var sender [20]byte
+			copy(sender[:], newTxs.senders[i*20:(i+1)*20])
+			if !cfg.Admission.Admit(tx, sender, newTxs.isLocal[i]) {
+				reasons[i] = PolicyRejected
+				emit(&MetaTx{Tx: tx}, TxRejected, 0, PolicyRejected)
+				continue
+			}
 		}
+		validTxs.appendFrom(&newTxs, i)
+		validIdx = append(validIdx, i)
 	}
 
-	unsafeAddToPool(senderInfo, newTxs, queued, func(i *MetaTx) {
+	discarded := map[*TxSlot]DiscardReason{}
+	var added []*MetaTx
+	addReasons := unsafeAddToPool(senderInfo, validTxs, queued, QueuedSubPool, cfg.PriceBump, cfg.AccountSlots, func(i *MetaTx) {
 		if _, ok := localsHistory.Get(i.Tx.idHash); ok {
 			//TODO: also check if sender is in list of local-senders
 			i.SubPool |= IsLocal
 		}
-		delete(byHash, string(i.Tx.idHash[:]))
+		if i.private {
+			i.privateSince = pendingHeight
+		}
+		byHash[string(i.Tx.idHash[:])] = i
+		added = append(added, i)
+	}, func(replaced *MetaTx) {
+		unsafeRemoveFromSubPool(replaced, pending, baseFee, queued)
+		delete(byHash, string(replaced.Tx.idHash[:]))
+		discarded[replaced.Tx] = Replaced
+		gcCandidates[replaced.Tx.senderID] = struct{}{}
+		emit(replaced, TxReplaced, 0, Replaced)
 	})
-
-	for i := range senderInfo {
-		// TODO: aggregate changed senders before call this func
-		onSenderChange(senderInfo[i], protocolBaseFee, blockBaseFee)
+	for j, reason := range addReasons {
+		reasons[validIdx[j]] = reason
+		if reason != NotSet && reason != AlreadyKnown {
+			emit(&MetaTx{Tx: validTxs.txs[j]}, TxRejected, 0, reason)
+		}
+	}
+	// new transactions start in queued sub-pool, updateSubPools reports their promotion
+	for _, mt := range added {
+		emit(mt, TxAdded, 0, NotSet)
 	}
 
-	pending.EnforceInvariants()
-	baseFee.EnforceInvariants()
-	queued.EnforceInvariants()
-
-	promote(pending, baseFee, queued, func(i *MetaTx) {
+	changedSenders := map[uint64]struct{}{}
+	for i, tx := range newTxs.txs {
+		if reasons[i] == NotSet {
+			changedSenders[tx.senderID] = struct{}{}
+		}
+	}
+	updateSubPools(cfg, senderInfo, changedSenders, protocolBaseFee, blockBaseFee, pending, baseFee, queued, func(i *MetaTx, reason DiscardReason) {
 		delete(byHash, string(i.Tx.idHash[:]))
 		senderInfo[i.Tx.senderID].txNonce2Tx.Delete(&nonce2TxItem{i})
 		if i.SubPool&IsLocal != 0 {
 			//TODO: only add to history if sender is not in list of local-senders
-			localsHistory.Add(i.Tx.idHash, struct{}{})
+			localsHistory.Add(i.Tx.idHash, i.Tx.senderID)
 		}
-	})
+		discarded[i.Tx] = reason
+		gcCandidates[i.Tx.senderID] = struct{}{}
+		emit(i, TxDiscarded, 0, reason)
+	}, movedEvent(emit))
 
-	return nil
+	for i, tx := range newTxs.txs {
+		if reasons[i] != NotSet {
+			continue
+		}
+		if reason, ok := discarded[tx]; ok {
+			reasons[i] = reason
+			continue
+		}
+		if mt, ok := byHash[string(tx.idHash[:])]; ok && mt.Tx == tx {
+			reasons[i] = Success
+		}
+	}
+	return reasons, nil
 }
-func (p *TxPool) OnNewBlock(unwindTxs, minedTxs TxSlots, protocolBaseFee, blockBaseFee uint64) error {
+
+// OnNewBlock - applies new block (or reverts one): stateChanges has new nonce and balance of accounts changed
+// by the block (keyed by 20-byte address), unwindTxs are transactions of reverted block, minedTxs - of applied one
+// (may be empty if they are unknown, see removeMined). head is the new head: applied block, or parent of reverted one. Base fee of the pending block is derived from it
+func (p *TxPool) OnNewBlock(stateChanges map[string]senderInfo, unwindTxs, minedTxs TxSlots, head BlockHeader, protocolBaseFee uint64) error {
+	blockHeight, blockBaseFee := head.Height, PendingBaseFee(p.cfg.rules, head)
 	p.lock.Lock()
 	defer p.lock.Unlock()
+	// markers of all senders depend on protocol base fee, types of transactions - on forks. Block base fee
+	// changes almost every block, transactions affected by it are updated without recalculation of senders
+	recalcAll := p.protocolBaseFee.Load() != protocolBaseFee || p.cfg.rules.forksChanged(p.blockHeight.Load()+1, blockHeight+1)
+	if !recalcAll && p.blockBaseFee.Load() != blockBaseFee {
+		onBaseFeeChange(blockBaseFee, p.pending, p.baseFee, p.queued)
+	}
 	p.protocolBaseFee.Store(protocolBaseFee)
 	p.blockBaseFee.Store(blockBaseFee)
+	p.blockHeight.Store(blockHeight)
 
-	setTxSenderID(p.senderIDs, p.senderInfo, unwindTxs)
-	setTxSenderID(p.senderIDs, p.senderInfo, minedTxs)
-	if err := onNewBlock(p.senderInfo, unwindTxs, minedTxs.txs, protocolBaseFee, blockBaseFee, p.pending, p.baseFee, p.queued, p.byHash, p.localsHistory); err != nil {
+	setTxSenderID(p.senderIDs, unwindTxs)
+	for _, tx := range unwindTxs.txs {
+		// re-injection may fail, for example if nonce was used by another transaction
+		p.gcCandidates[tx.senderID] = struct{}{}
+	}
+	// pool has nothing to remove for unknown senders of mined transactions, ids are not allocated for them
+	mined := make([]*TxSlot, 0, len(minedTxs.txs))
+	for i, tx := range minedTxs.txs {
+		if id, ok := p.senderIDs.id(string(minedTxs.senders[i*20 : (i+1)*20])); ok {
+			tx.senderID = id
+			mined = append(mined, tx)
+		}
+	}
+	changedSenders := make(map[uint64]senderInfo, len(stateChanges))
+	for addr, info := range stateChanges {
+		id, ok := p.senderIDs.id(addr)
+		if !ok {
+			// pool has no transactions of this sender
+			continue
+		}
+		changedSenders[id] = info
+		// state of senders of re-injected transactions comes with the block, no need to load it
+		if _, ok = p.senderInfo[id]; !ok {
+			p.senderInfo[id] = newSenderInfo(info.nonce, info.balance)
+		}
+	}
+	// re-injected transactions of unknown senders wait for the state same way as new transactions
+	unwindTxs = p.holdUnknownSenders(unwindTxs)
+	if err := onNewBlock(p.cfg, p.senderInfo, changedSenders, unwindTxs, mined, protocolBaseFee, blockBaseFee, blockHeight+1, recalcAll, p.pending, p.baseFee, p.queued, p.byHash, p.localsHistory, p.gcCandidates, p.onEvent); err != nil {
 		return err
 	}
+	notifyNewTxs := p.expirePrivate(blockHeight + 1)
+	p.gcSenders()
 
-	notifyNewTxs := make(Hashes, 0, 32*len(unwindTxs.txs))
 	for i := range unwindTxs.txs {
-		_, ok := p.byHash[string(unwindTxs.txs[i].idHash[:])]
-		if !ok {
+		mt, ok := p.byHash[string(unwindTxs.txs[i].idHash[:])]
+		if !ok || mt.private {
 			continue
 		}
 		notifyNewTxs = append(notifyNewTxs, unwindTxs.txs[i].idHash[:]...)
@@ -293,23 +830,99 @@ func (p *TxPool) OnNewBlock(unwindTxs, minedTxs TxSlots, protocolBaseFee, blockB
 
 	return nil
 }
-func setTxSenderID(senderIDs map[string]uint64, senderInfo map[uint64]*senderInfo, txs TxSlots) {
+
+// expirePrivate - private transactions which stayed private for TxPoolConfig.PrivateTxBlocks are dropped, or
+// become ordinary local ones if TxPoolConfig.PublishExpiredPrivate is set: hashes of these are returned to be
+// announced. Must be called under lock
+func (p *TxPool) expirePrivate(pendingHeight uint64) (published Hashes) {
+	if p.cfg.PrivateTxBlocks == 0 {
+		return nil
+	}
+	var expired []*MetaTx
+	for _, mt := range p.byHash {
+		// pending height goes back on unwind
+		if !mt.private || pendingHeight < mt.privateSince || pendingHeight-mt.privateSince < p.cfg.PrivateTxBlocks {
+			continue
+		}
+		if p.cfg.PublishExpiredPrivate {
+			mt.private = false
+			published = append(published, mt.Tx.idHash[:]...)
+			continue
+		}
+		expired = append(expired, mt)
+	}
+	p.discardLocked(expired, PrivateExpired)
+	return published
+}
+
+// discardLocked - removes transactions from the pool because of reason. Then sub-pools of their senders are
+// recalculated: removed transactions make nonce gaps. Must be called under lock
+func (p *TxPool) discardLocked(txs []*MetaTx, reason DiscardReason) {
+	if len(txs) == 0 {
+		return
+	}
+	discard := func(mt *MetaTx, reason DiscardReason) {
+		delete(p.byHash, string(mt.Tx.idHash[:]))
+		p.senderInfo[mt.Tx.senderID].txNonce2Tx.Delete(&nonce2TxItem{mt})
+		p.gcCandidates[mt.Tx.senderID] = struct{}{}
+		p.onEvent(mt, TxDiscarded, 0, reason)
+	}
+	touched := map[uint64]struct{}{}
+	for _, mt := range txs {
+		unsafeRemoveFromSubPool(mt, p.pending, p.baseFee, p.queued)
+		discard(mt, reason)
+		touched[mt.Tx.senderID] = struct{}{}
+	}
+	updateSubPools(p.cfg, p.senderInfo, touched, p.protocolBaseFee.Load(), p.blockBaseFee.Load(), p.pending, p.baseFee, p.queued, discard, movedEvent(p.onEvent))
+}
+
+func setTxSenderID(senderIDs *sendersRegistry, txs TxSlots) {
 	for i := range txs.txs {
-		id, ok := senderIDs[string(txs.senders[i*20:(i+1)*20])]
-		if !ok {
-			for i := range senderInfo { //TODO: create field for it?
-				if id < i {
-					id = i
+		txs.txs[i].senderID = senderIDs.getOrCreateID(string(txs.senders[i*20 : (i+1)*20]))
+	}
+}
+
+// gcSenders - forgets senders which have no transactions in the pool, no transactions waiting for their state
+// and no local transactions in localsHistory: their ids and state. State is loaded again if new transaction of
+// such sender arrives. Only gcCandidates are checked: senders which lost transactions since the last call.
+// Must be called under lock
+func (p *TxPool) gcSenders() {
+	var withLocalsHistory map[uint64]struct{}
+	for id := range p.gcCandidates {
+		delete(p.gcCandidates, id)
+		if _, ok := p.waitingSenders[id]; ok {
+			continue
+		}
+		if info, ok := p.senderInfo[id]; ok && info.txNonce2Tx.Len() > 0 {
+			continue
+		}
+		if withLocalsHistory == nil {
+			withLocalsHistory = map[uint64]struct{}{}
+			for _, hash := range p.localsHistory.Keys() {
+				if senderID, ok := p.localsHistory.Peek(hash); ok {
+					if id, ok := senderID.(uint64); ok {
+						withLocalsHistory[id] = struct{}{}
+					}
 				}
 			}
-			id++
-			senderIDs[string(txs.senders[i*20:(i+1)*20])] = id
 		}
-		txs.txs[i].senderID = id
+		if _, ok := withLocalsHistory[id]; ok {
+			// keeps state for re-injection of its transactions at unwind, evicted from history - becomes candidate again
+			continue
+		}
+		delete(p.senderInfo, id)
+		p.senderIDs.remove(id)
 	}
 }
 
-func onNewBlock(senderInfo map[uint64]*senderInfo, unwindTxs TxSlots, minedTxs []*TxSlot, protocolBaseFee, blockBaseFee uint64, pending, baseFee, queued *SubPool, byHash map[string]*MetaTx, localsHistory *lru.Cache) error {
+// onNewBlock - if recalcAll (protocol base fee changed or fork activated or reverted at block pendingHeight), ordering keys
+// of all transactions are recalculated (and heaps re-built in O(n)), and transactions of types not supported
+// anymore are discarded. Otherwise - only of senders touched by the block. emit receives every change of
+// transactions in the pool, can be nil
+func onNewBlock(cfg TxPoolConfig, senderInfo map[uint64]*senderInfo, stateChanges map[uint64]senderInfo, unwindTxs TxSlots, minedTxs []*TxSlot, protocolBaseFee, blockBaseFee, pendingHeight uint64, recalcAll bool, pending, baseFee, queued *SubPool, byHash map[string]*MetaTx, localsHistory *lru.Cache, gcCandidates map[uint64]struct{}, emit eventFunc) error {
+	if emit == nil {
+		emit = noEvents
+	}
 	for i := range unwindTxs.txs {
 		if unwindTxs.txs[i].senderID == 0 {
 			return fmt.Errorf("onNewBlock.unwindTxs: senderID can't be zero")
@@ -321,12 +934,29 @@ func onNewBlock(senderInfo map[uint64]*senderInfo, unwindTxs TxSlots, minedTxs [
 		}
 	}
 
-	removeMined(senderInfo, minedTxs, pending, baseFee, queued, func(i *MetaTx) {
+	changedSenders := make([]uint64, 0, len(stateChanges))
+	for id, info := range stateChanges {
+		sender, ok := senderInfo[id]
+		if !ok {
+			// state of the sender is being loaded - block has newer one, loaded state will be ignored
+			senderInfo[id] = newSenderInfo(info.nonce, info.balance)
+			continue
+		}
+		sender.nonce, sender.balance = info.nonce, info.balance
+		changedSenders = append(changedSenders, id)
+	}
+
+	removeMined(senderInfo, minedTxs, changedSenders, pending, baseFee, queued, gcCandidates, func(i *MetaTx, reason DiscardReason) {
 		delete(byHash, string(i.Tx.idHash[:]))
 		senderInfo[i.Tx.senderID].txNonce2Tx.Delete(&nonce2TxItem{i})
 		if i.SubPool&IsLocal != 0 {
 			//TODO: only add to history if sender is not in list of local-senders
-			localsHistory.Add(i.Tx.idHash, struct{}{})
+			localsHistory.Add(i.Tx.idHash, i.Tx.senderID)
+		}
+		if reason == Mined {
+			emit(i, TxMined, 0, reason)
+		} else {
+			emit(i, TxDiscarded, 0, reason)
 		}
 	})
 
@@ -341,35 +971,71 @@ func onNewBlock(senderInfo map[uint64]*senderInfo, unwindTxs TxSlots, minedTxs [
 	// time (up to some "immutability threshold").
 	if len(unwindTxs.txs) > 0 {
 		//TODO: restore isLocal flag in unwindTxs
-		unsafeAddToPool(senderInfo, unwindTxs, pending, func(i *MetaTx) {
+		var reinjected []*MetaTx
+		unsafeAddToPool(senderInfo, unwindTxs, pending, PendingSubPool, cfg.PriceBump, cfg.AccountSlots, func(i *MetaTx) {
 			//fmt.Printf("add: %d,%d\n", i.Tx.senderID, i.Tx.nonce)
 			if _, ok := localsHistory.Get(i.Tx.idHash); ok {
 				//TODO: also check if sender is in list of local-senders
 				i.SubPool |= IsLocal
 			}
-			delete(byHash, string(i.Tx.idHash[:]))
+			byHash[string(i.Tx.idHash[:])] = i
+			reinjected = append(reinjected, i)
+		}, func(replaced *MetaTx) {
+			unsafeRemoveFromSubPool(replaced, pending, baseFee, queued)
+			delete(byHash, string(replaced.Tx.idHash[:]))
+			emit(replaced, TxReplaced, 0, Replaced)
 		})
+		for _, mt := range reinjected {
+			emit(mt, TxReinjected, 0, NotSet)
+		}
 	}
 
-	for i := range senderInfo {
-		// TODO: aggregate changed senders before call this func
-		onSenderChange(senderInfo[i], protocolBaseFee, blockBaseFee)
+	if recalcAll {
+		// for example typed transactions re-injected by unwind to the block before the fork
+		var unsupported []*MetaTx
+		for _, mt := range byHash {
+			if !cfg.rules.TxTypeSupported(mt.Tx.txType, pendingHeight) {
+				unsupported = append(unsupported, mt)
+			}
+		}
+		for _, mt := range unsupported {
+			unsafeRemoveFromSubPool(mt, pending, baseFee, queued)
+			delete(byHash, string(mt.Tx.idHash[:]))
+			senderInfo[mt.Tx.senderID].txNonce2Tx.Delete(&nonce2TxItem{mt})
+			gcCandidates[mt.Tx.senderID] = struct{}{}
+			emit(mt, TxDiscarded, 0, TxTypeNotSupported)
+		}
 	}
 
-	pending.EnforceInvariants()
-	baseFee.EnforceInvariants()
-	queued.EnforceInvariants()
+	touched := map[uint64]struct{}{}
+	if recalcAll {
+		for id := range senderInfo {
+			touched[id] = struct{}{}
+		}
+	} else {
+		for id := range stateChanges {
+			touched[id] = struct{}{}
+		}
+		for _, tx := range minedTxs {
+			touched[tx.senderID] = struct{}{}
+		}
+		for _, tx := range unwindTxs.txs {
+			touched[tx.senderID] = struct{}{}
+		}
+	}
 
-	promote(pending, baseFee, queued, func(i *MetaTx) {
+	updateSubPools(cfg, senderInfo, touched, protocolBaseFee, blockBaseFee, pending, baseFee, queued, func(i *MetaTx, reason DiscardReason) {
 		//fmt.Printf("del1 nonce: %d, %t\n", i.Tx.senderID, senderInfo[i.Tx.senderID].nonce < i.Tx.nonce)
 		//fmt.Printf("del2 balance: %x,%x,%x\n", i.Tx.value, i.Tx.tip, senderInfo[i.Tx.senderID].balance)
 		delete(byHash, string(i.Tx.idHash[:]))
 		senderInfo[i.Tx.senderID].txNonce2Tx.Delete(&nonce2TxItem{i})
 		if i.SubPool&IsLocal != 0 {
 			//TODO: only add to history if sender is not in list of local-senders
-			localsHistory.Add(i.Tx.idHash, struct{}{})
+			localsHistory.Add(i.Tx.idHash, i.Tx.senderID)
 		}
-	})
+		gcCandidates[i.Tx.senderID] = struct{}{}
+		emit(i, TxDiscarded, 0, reason)
+	}, movedEvent(emit))
 
 	return nil
 }
@@ -381,68 +1047,250 @@ func onNewBlock(senderInfo map[uint64]*senderInfo, unwindTxs TxSlots, minedTxs [
 // modify state_balance and state_nonce, potentially remove some elements (if transaction with some nonce is
 // included into a block), and finally, walk over the transaction records and update SubPool fields depending on
 // the actual presence of nonce gaps and what the balance is.
-func removeMined(senderInfo map[uint64]*senderInfo, minedTxs []*TxSlot, pending, baseFee, queued *SubPool, discard func(tx *MetaTx)) {
+//
+// Transactions with nonce lower than state nonce of their sender can't be included anymore, so they are removed
+// for senders of minedTxs and for changedSenders (their state nonce was updated by the block). discard gets Mined
+// for transactions of minedTxs and NonceTooLow for the rest. Without minedTxs (caller, like BlockStream, knows
+// only state changes of the block) all of them get Mined: nonce was used by the block, most likely by the pooled
+// transaction itself. Senders which lost transactions become gcCandidates
+func removeMined(senderInfo map[uint64]*senderInfo, minedTxs []*TxSlot, changedSenders []uint64, pending, baseFee, queued *SubPool, gcCandidates map[uint64]struct{}, discard func(tx *MetaTx, reason DiscardReason)) {
+	mined := make(map[[32]byte]struct{}, len(minedTxs))
 	for _, tx := range minedTxs {
+		mined[tx.idHash] = struct{}{}
 		sender, ok := senderInfo[tx.senderID]
 		if !ok {
-			panic("not implemented yet")
+			// pool has no transactions of this sender
+			continue
 		}
-		// delete mined transactions from everywhere
+		// mined transaction means state nonce is already above it, even if state changes were not delivered
+		if sender.nonce <= tx.nonce {
+			sender.nonce = tx.nonce + 1
+		}
+		changedSenders = append(changedSenders, tx.senderID)
+	}
+	var stale []*MetaTx
+	for _, id := range changedSenders {
+		sender := senderInfo[id]
+		// btree can't be modified while iterating, so collect first
+		stale = stale[:0]
 		sender.txNonce2Tx.Ascend(func(i btree.Item) bool {
 			it := i.(*nonce2TxItem)
-			if it.MetaTx.Tx.nonce > sender.nonce {
+			if it.MetaTx.Tx.nonce >= sender.nonce {
 				return false
 			}
-			// TODO: save local transactions to cache with TTL, in case of re-org - to restore isLocal flag of re-injected transactions
-
+			stale = append(stale, it.MetaTx)
+			return true
+		})
+		if len(stale) > 0 {
+			gcCandidates[id] = struct{}{}
+		}
+		// delete mined transactions from everywhere
+		for _, mt := range stale {
 			// del from nonce2tx mapping
-			sender.txNonce2Tx.Delete(i)
+			sender.txNonce2Tx.Delete(&nonce2TxItem{mt})
+			reason := NonceTooLow
+			if _, ok := mined[mt.Tx.idHash]; ok || len(minedTxs) == 0 {
+				reason = Mined
+			}
 			// del from sub-pool
-			switch it.MetaTx.currentSubPool {
+			switch mt.currentSubPool {
 			case PendingSubPool:
-				pending.UnsafeRemove(it.MetaTx)
-				discard(it.MetaTx)
+				pending.UnsafeRemove(mt)
+				discard(mt, reason)
 			case BaseFeeSubPool:
-				baseFee.UnsafeRemove(it.MetaTx)
-				discard(it.MetaTx)
+				baseFee.UnsafeRemove(mt)
+				discard(mt, reason)
 			case QueuedSubPool:
-				queued.UnsafeRemove(it.MetaTx)
-				discard(it.MetaTx)
+				queued.UnsafeRemove(mt)
+				discard(mt, reason)
 			default:
 				//already removed
 			}
-			return true
-		})
+		}
 	}
 }
 
 // unwind
-func unsafeAddToPool(senderInfo map[uint64]*senderInfo, unwindTxs TxSlots, to *SubPool, beforeAdd func(tx *MetaTx)) {
+// unsafeAddToPool - returns reasons of skipping transactions, NotSet for added ones. Transaction with the same
+// sender and nonce is replaced only if new one pays priceBump percents more (see replacementUnderpriced), replaced
+// transaction is passed to onReplace - which must remove it from sub-pools and byHash. Non-local transactions of
+// one sender can't occupy more than accountSlots
+func unsafeAddToPool(senderInfo map[uint64]*senderInfo, unwindTxs TxSlots, to *SubPool, subPoolType SubPoolType, priceBump uint64, accountSlots int, beforeAdd func(tx *MetaTx), onReplace func(replaced *MetaTx)) (reasons []DiscardReason) {
+	reasons = make([]DiscardReason, len(unwindTxs.txs))
 	for i, tx := range unwindTxs.txs {
 		sender, ok := senderInfo[tx.senderID]
 		if !ok {
-			panic("not implemented yet")
+			// state of the sender is not loaded yet, callers must hold back such transactions
+			continue
+		}
+		if tx.nonce < sender.nonce {
+			reasons[i] = NonceTooLow
+			continue
 		}
 
-		mt := newMetaTx(tx, unwindTxs.isLocal[i])
+		mt := newMetaTx(tx, unwindTxs.isLocal[i], unwindTxs.private[i])
 		// Insert to pending pool, if pool doesn't have tx with same Nonce and bigger Tip
+		var foundMt *MetaTx
 		if found := sender.txNonce2Tx.Get(&nonce2TxItem{mt}); found != nil {
-			if tx.tip <= found.(*nonce2TxItem).MetaTx.Tx.tip {
+			foundMt = found.(*nonce2TxItem).MetaTx
+			if foundMt.Tx.idHash == tx.idHash {
+				reasons[i] = AlreadyKnown
+				continue
+			}
+			if replacementUnderpriced(foundMt.Tx, tx, priceBump) {
+				reasons[i] = ReplaceUnderpriced
 				continue
 			}
 		}
+		if !unwindTxs.isLocal[i] {
+			slots := senderSlots(sender) + txSlots(tx)
+			if foundMt != nil && foundMt.SubPool&IsLocal == 0 {
+				slots -= txSlots(foundMt.Tx)
+			}
+			if slots > accountSlots {
+				reasons[i] = SenderPoolOverflow
+				continue
+			}
+		}
+		if foundMt != nil {
+			onReplace(foundMt)
+		}
 		beforeAdd(mt)
 		sender.txNonce2Tx.ReplaceOrInsert(&nonce2TxItem{mt})
-		to.UnsafeAdd(mt, PendingSubPool)
+		to.UnsafeAdd(mt, subPoolType)
+	}
+	return reasons
+}
+
+// senderSlots - slots occupied by non-local transactions of the sender
+func senderSlots(sender *senderInfo) (slots int) {
+	sender.txNonce2Tx.Ascend(func(i btree.Item) bool {
+		if mt := i.(*nonce2TxItem).MetaTx; mt.SubPool&IsLocal == 0 {
+			slots += txSlots(mt.Tx)
+		}
+		return true
+	})
+	return slots
+}
+
+// Gas costs of transaction, which are paid before execution starts (Istanbul and Berlin rules)
+const (
+	txGas                     uint64 = 21000
+	txGasContractCreation     uint64 = 53000
+	txDataZeroGas             uint64 = 4
+	txDataNonZeroGas          uint64 = 16
+	txAccessListAddressGas    uint64 = 2400
+	txAccessListStorageKeyGas uint64 = 1900
+)
+
+// CalcIntrinsicGas - gas which transaction spends before execution of its code: base cost, cost of data and
+// of access list. Lengths are limited by size of transaction, so it can't overflow
+func CalcIntrinsicGas(dataLen, dataNonZero, alAddrCount, alStorCount uint64, creation bool) uint64 {
+	gas := txGas
+	if creation {
+		gas = txGasContractCreation
+	}
+	gas += dataNonZero*txDataNonZeroGas + (dataLen-dataNonZero)*txDataZeroGas
+	gas += alAddrCount*txAccessListAddressGas + alStorCount*txAccessListStorageKeyGas
+	return gas
+}
+
+// validateTx - checks which don't depend on state of the sender and content of the pool. Returns NotSet
+// if transaction is valid. Type of transaction is checked against rules of block pendingHeight. Signature and
+// chainId are checked by TxParseContext
+func validateTx(cfg TxPoolConfig, tx *TxSlot, pendingHeight uint64) DiscardReason {
+	if !cfg.rules.TxTypeSupported(tx.txType, pendingHeight) {
+		return TxTypeNotSupported
+	}
+	if uint64(len(tx.rlp)) > cfg.MaxTxSize {
+		return OversizedData
+	}
+	if tx.gas > cfg.BlockGasLimit {
+		return GasLimitTooHigh
+	}
+	if tx.gas < CalcIntrinsicGas(uint64(tx.dataLen), uint64(tx.dataNonZero), uint64(tx.alAddrCount), uint64(tx.alStorCount), tx.creation) {
+		return IntrinsicGas
+	}
+	if tx.tip > tx.feeCap {
+		return TipAboveFeeCap
+	}
+	return NotSet
+}
+
+// replacementUnderpriced - same rules as in geth: newTx must pay at least priceBump percents more than oldTx,
+// both in tip and in feeCap
+func replacementUnderpriced(oldTx, newTx *TxSlot, priceBump uint64) bool {
+	if newTx.tip <= oldTx.tip || newTx.feeCap <= oldTx.feeCap {
+		return true
+	}
+	bump := uint256.NewInt(100 + priceBump)
+	hundred := uint256.NewInt(100)
+	minTip := uint256.NewInt(oldTx.tip)
+	minTip.Mul(minTip, bump).Div(minTip, hundred)
+	minFeeCap := uint256.NewInt(oldTx.feeCap)
+	minFeeCap.Mul(minFeeCap, bump).Div(minFeeCap, hundred)
+	return uint256.NewInt(newTx.tip).Lt(minTip) || uint256.NewInt(newTx.feeCap).Lt(minFeeCap)
+}
+
+// unsafeRemoveFromSubPool - removes transaction from sub-pool it currently belongs to. Breaks heap invariants
+// same way as SubPool.UnsafeRemove
+func unsafeRemoveFromSubPool(mt *MetaTx, pending, baseFee, queued *SubPool) {
+	switch mt.currentSubPool {
+	case PendingSubPool:
+		pending.UnsafeRemove(mt)
+	case BaseFeeSubPool:
+		baseFee.UnsafeRemove(mt)
+	case QueuedSubPool:
+		queued.UnsafeRemove(mt)
+	default:
+		//already removed
+	}
+}
+
+// updateSubPools - recalculates markers and ordering keys of transactions of changedSenders, then moves
+// transactions between sub-pools. Discarded transaction makes nonce gap for next transactions of its sender,
+// so senders of discarded transactions are recalculated again. moved is passed to promote
+func updateSubPools(cfg TxPoolConfig, senderInfo map[uint64]*senderInfo, changedSenders map[uint64]struct{}, protocolBaseFee, blockBaseFee uint64, pending, baseFee, queued *SubPool, discard func(tx *MetaTx, reason DiscardReason), moved func(tx *MetaTx, from SubPoolType)) {
+	for {
+		for id := range changedSenders {
+			if sender, ok := senderInfo[id]; ok {
+				onSenderChange(sender, protocolBaseFee, blockBaseFee)
+			}
+		}
+
+		pending.EnforceInvariants()
+		baseFee.EnforceInvariants()
+		queued.EnforceInvariants()
+
+		discardedSenders := map[uint64]struct{}{}
+		promote(cfg, pending, baseFee, queued, func(tx *MetaTx, reason DiscardReason) {
+			discard(tx, reason)
+			discardedSenders[tx.Tx.senderID] = struct{}{}
+		}, moved)
+		if len(discardedSenders) == 0 {
+			return
+		}
+		changedSenders = discardedSenders
 	}
 }
 
 func onSenderChange(sender *senderInfo, protocolBaseFee, blockBaseFee uint64) {
-	prevNonce := -1
+	noGapsNonce := sender.nonce
 	accumulatedSenderSpent := uint256.NewInt(0)
+	minTip, minFeeCap := uint64(math.MaxUint64), uint64(math.MaxUint64)
 	sender.txNonce2Tx.Ascend(func(i btree.Item) bool {
 		it := i.(*nonce2TxItem)
 
+		// Ordering inside sub-pools: by effective tip, but transactions of the same sender are kept in nonce order
+		if it.MetaTx.Tx.tip < minTip {
+			minTip = it.MetaTx.Tx.tip
+		}
+		if it.MetaTx.Tx.feeCap < minFeeCap {
+			minFeeCap = it.MetaTx.Tx.feeCap
+		}
+		it.MetaTx.minTip, it.MetaTx.minFeeCap = minTip, minFeeCap
+		it.MetaTx.effectiveTip = effectiveTip(minTip, minFeeCap, blockBaseFee)
+
 		// Sender has enough balance for: gasLimit x feeCap + transferred_value
 		needBalance := (&it.MetaTx.NeedBalance).SetUint64(0)
 		needBalance.Mul(uint256.NewInt(it.MetaTx.Tx.gas), uint256.NewInt(it.MetaTx.Tx.feeCap))
@@ -454,17 +1302,17 @@ func onSenderChange(sender *senderInfo, protocolBaseFee, blockBaseFee uint64) {
 		// this transaction will never be included into this particular chain.
 		it.MetaTx.SubPool &^= EnoughFeeCapProtocol
 		if it.MetaTx.Tx.feeCap >= protocolBaseFee {
-			it.MetaTx.SubPool &= EnoughFeeCapProtocol
+			it.MetaTx.SubPool |= EnoughFeeCapProtocol
 		}
 
 		// 2. Absence of nonce gaps. Set to 1 for transactions whose nonce is N, state nonce for
 		// the sender is M, and there are transactions for all nonces between M and N from the same
 		// sender. Set to 0 is the transaction's nonce is divided from the state nonce by one or more nonce gaps.
 		it.MetaTx.SubPool &^= NoNonceGaps
-		if prevNonce == -1 || uint64(prevNonce)+1 == it.MetaTx.Tx.nonce {
-			it.MetaTx.SubPool &= NoNonceGaps
+		if noGapsNonce == it.MetaTx.Tx.nonce {
+			it.MetaTx.SubPool |= NoNonceGaps
+			noGapsNonce++
 		}
-		prevNonce = int(it.Tx.nonce)
 
 		// 3. Sufficient balance for gas. Set to 1 if the balance of sender's account in the
 		// state is B, nonce of the sender in the state is M, nonce of the transaction is N, and the
@@ -473,16 +1321,17 @@ func onSenderChange(sender *senderInfo, protocolBaseFee, blockBaseFee uint64) {
 		// set if there is currently a guarantee that the transaction and all its required prior
 		// transactions will be able to pay for gas.
 		it.MetaTx.SubPool &^= EnoughBalance
+		accumulatedSenderSpent.Add(accumulatedSenderSpent, needBalance) // already deleted all transactions with nonce <= sender.nonce
 		if sender.balance.Gt(accumulatedSenderSpent) || sender.balance.Eq(accumulatedSenderSpent) {
-			it.MetaTx.SubPool &= EnoughBalance
+			it.MetaTx.SubPool |= EnoughBalance
 		}
-		accumulatedSenderSpent.Add(accumulatedSenderSpent, needBalance) // already deleted all transactions with nonce <= sender.nonce
 
 		// 4. Dynamic fee requirement. Set to 1 if feeCap of the transaction is no less than
 		// baseFee of the currently pending block. Set to 0 otherwise.
+		// Transaction can't be included before previous transactions of the sender, so their feeCap matters too
 		it.MetaTx.SubPool &^= EnoughFeeCapBlock
-		if it.MetaTx.Tx.feeCap >= blockBaseFee {
-			it.MetaTx.SubPool &= EnoughFeeCapBlock
+		if it.MetaTx.minFeeCap >= blockBaseFee {
+			it.MetaTx.SubPool |= EnoughFeeCapBlock
 		}
 
 		// 5. Local transaction. Set to 1 if transaction is local.
@@ -492,7 +1341,39 @@ func onSenderChange(sender *senderInfo, protocolBaseFee, blockBaseFee uint64) {
 	})
 }
 
-func promote(pending, baseFee, queued *SubPool, discard func(tx *MetaTx)) {
+// onBaseFeeChange - updates EnoughFeeCapBlock marker and effectiveTip of transactions when only block base fee has
+// changed. Senders are not recalculated: both depend only on minTip and minFeeCap, which stay the same. Changed
+// are transactions which cross the base fee boundary and transactions whose tip is limited by fee cap, only they
+// are re-sifted in heaps of their sub-pools. Transactions which crossed the boundary are moved by promote
+func onBaseFeeChange(blockBaseFee uint64, pending, baseFee, queued *SubPool) {
+	for _, sub := range []*SubPool{pending, baseFee, queued} {
+		var changed []*MetaTx
+		for _, mt := range *sub.best {
+			enough := mt.minFeeCap >= blockBaseFee
+			if enough != (mt.SubPool&EnoughFeeCapBlock != 0) || effectiveTip(mt.minTip, mt.minFeeCap, blockBaseFee) != mt.effectiveTip {
+				changed = append(changed, mt)
+			}
+		}
+		// one at a time: heap.Fix restores order of one changed transaction
+		for _, mt := range changed {
+			mt.SubPool &^= EnoughFeeCapBlock
+			if mt.minFeeCap >= blockBaseFee {
+				mt.SubPool |= EnoughFeeCapBlock
+			}
+			mt.effectiveTip = effectiveTip(mt.minTip, mt.minFeeCap, blockBaseFee)
+			sub.Fix(mt)
+		}
+	}
+}
+
+// promote - moves transactions between sub-pools according to their markers and limits of sub-pools. moved is
+// called for every transaction which changed sub-pool, with sub-pool it left
+func promote(cfg TxPoolConfig, pending, baseFee, queued *SubPool, discard func(tx *MetaTx, reason DiscardReason), moved func(tx *MetaTx, from SubPoolType)) {
+	move := func(mt *MetaTx, from SubPoolType, to *SubPool, toType SubPoolType) {
+		to.Add(mt, toType)
+		moved(mt, from)
+	}
+
 	//1. If top element in the worst green queue has SubPool != 0b1111 (binary), it needs to be removed from the green pool.
 	//   If SubPool < 0b1000 (not satisfying minimum fee), discard.
 	//   If SubPool == 0b1110, demote to the yellow pool, otherwise demote to the red pool.
@@ -501,30 +1382,25 @@ func promote(pending, baseFee, queued *SubPool, discard func(tx *MetaTx)) {
 			break
 		}
 		if worst.SubPool >= 0b11100 {
-			baseFee.Add(pending.PopWorst(), BaseFeeSubPool)
+			move(pending.PopWorst(), PendingSubPool, baseFee, BaseFeeSubPool)
 			continue
 		}
 		if worst.SubPool >= 0b10000 {
-			queued.Add(pending.PopWorst(), QueuedSubPool)
+			move(pending.PopWorst(), PendingSubPool, queued, QueuedSubPool)
 			continue
 		}
-		discard(pending.PopWorst())
+		discard(pending.PopWorst(), FeeTooLow)
 	}
 
 	//2. If top element in the worst green queue has SubPool == 0b1111, but there is not enough room in the pool, discard.
-	for worst := pending.Worst(); pending.Len() > PendingSubPoolLimit; worst = pending.Worst() {
-		if worst.SubPool >= 0b11111 { // TODO: here must 'SubPool == 0b1111' or 'SubPool <= 0b1111' ?
-			break
-		}
-		pending.PopWorst()
-	}
+	evictWorst(pending, cfg.PendingSubPoolLimit, PendingPoolOverflow, discard)
 
 	//3. If the top element in the best yellow queue has SubPool == 0b1111, promote to the green pool.
 	for best := baseFee.Best(); baseFee.Len() > 0; best = baseFee.Best() {
 		if best.SubPool < 0b11110 {
 			break
 		}
-		pending.Add(baseFee.PopBest(), PendingSubPool)
+		move(baseFee.PopBest(), BaseFeeSubPool, pending, PendingSubPool)
 	}
 
 	//4. If the top element in the worst yellow queue has SubPool != 0x1110, it needs to be removed from the yellow pool.
@@ -534,19 +1410,14 @@ func promote(pending, baseFee, queued *SubPool, discard func(tx *MetaTx)) {
 			break
 		}
 		if worst.SubPool >= 0b10000 {
-			queued.Add(baseFee.PopWorst(), QueuedSubPool)
+			move(baseFee.PopWorst(), BaseFeeSubPool, queued, QueuedSubPool)
 			continue
 		}
-		discard(baseFee.PopWorst())
+		discard(baseFee.PopWorst(), FeeTooLow)
 	}
 
 	//5. If the top element in the worst yellow queue has SubPool == 0x1110, but there is not enough room in the pool, discard.
-	for worst := baseFee.Worst(); baseFee.Len() > BaseFeeSubPoolLimit; worst = baseFee.Worst() {
-		if worst.SubPool >= 0b11110 {
-			break
-		}
-		discard(baseFee.PopWorst())
-	}
+	evictWorst(baseFee, cfg.BaseFeeSubPoolLimit, BaseFeePoolOverflow, discard)
 
 	//6. If the top element in the best red queue has SubPool == 0x1110, promote to the yellow pool. If SubPool == 0x1111, promote to the green pool.
 	for best := queued.Best(); queued.Len() > 0; best = queued.Best() {
@@ -554,11 +1425,11 @@ func promote(pending, baseFee, queued *SubPool, discard func(tx *MetaTx)) {
 			break
 		}
 		if best.SubPool < 0b11110 {
-			baseFee.Add(queued.PopBest(), BaseFeeSubPool)
+			move(queued.PopBest(), QueuedSubPool, baseFee, BaseFeeSubPool)
 			continue
 		}
 
-		pending.Add(queued.PopBest(), PendingSubPool)
+		move(queued.PopBest(), QueuedSubPool, pending, PendingSubPool)
 	}
 
 	//7. If the top element in the worst red queue has SubPool < 0b1000 (not satisfying minimum fee), discard.
@@ -567,18 +1438,37 @@ func promote(pending, baseFee, queued *SubPool, discard func(tx *MetaTx)) {
 			break
 		}
 
-		discard(queued.PopWorst())
+		discard(queued.PopWorst(), FeeTooLow)
 	}
 
 	//8. If the top element in the worst red queue has SubPool >= 0b100, but there is not enough room in the pool, discard.
-	for _ = queued.Worst(); queued.Len() > QueuedSubPoolLimit; _ = queued.Worst() {
-		discard(queued.PopWorst())
+	evictWorst(queued, cfg.QueuedSubPoolLimit, QueuedPoolOverflow, discard)
+}
+
+// evictWorst - discards the worst transactions of the sub-pool until it fits into limit of slots. Sub-pool is
+// ordered across senders, so senders with many transactions lose their worst ones first. Local transactions
+// are exempted: they are put back
+func evictWorst(sub *SubPool, limit int, reason DiscardReason, discard func(tx *MetaTx, reason DiscardReason)) {
+	var locals []*MetaTx
+	var localsSubPool SubPoolType
+	for sub.Slots() > limit {
+		subPoolType := sub.Worst().currentSubPool // PopWorst resets it
+		worst := sub.PopWorst()
+		if worst.SubPool&IsLocal != 0 {
+			locals, localsSubPool = append(locals, worst), subPoolType
+			continue
+		}
+		discard(worst, reason)
+	}
+	for _, mt := range locals {
+		sub.Add(mt, localsSubPool)
 	}
 }
 
 type SubPool struct {
 	best  *BestQueue
 	worst *WorstQueue
+	slots int // occupied by non-local transactions, see txSlots
 }
 
 func NewSubPool() *SubPool {
@@ -604,18 +1494,22 @@ func (p *SubPool) Worst() *MetaTx {
 func (p *SubPool) PopBest() *MetaTx {
 	i := heap.Pop(p.best).(*MetaTx)
 	heap.Remove(p.worst, i.worstIndex)
+	p.slots -= slotsOf(i)
 	return i
 }
 func (p *SubPool) PopWorst() *MetaTx {
 	i := heap.Pop(p.worst).(*MetaTx)
 	heap.Remove(p.best, i.bestIndex)
+	p.slots -= slotsOf(i)
 	return i
 }
-func (p *SubPool) Len() int { return p.best.Len() }
+func (p *SubPool) Len() int   { return p.best.Len() }
+func (p *SubPool) Slots() int { return p.slots }
 func (p *SubPool) Add(i *MetaTx, subPoolType SubPoolType) {
 	i.currentSubPool = subPoolType
 	heap.Push(p.best, i)
 	heap.Push(p.worst, i)
+	p.slots += slotsOf(i)
 }
 
 // UnsafeRemove - does break Heap invariants, but it has O(1) instead of O(log(n)) complexity.
@@ -627,12 +1521,28 @@ func (p *SubPool) UnsafeRemove(i *MetaTx) *MetaTx {
 	p.worst.Pop()
 	p.best.Swap(i.bestIndex, p.best.Len()-1)
 	p.best.Pop()
+	p.slots -= slotsOf(i)
 	return i
 }
+
+// Fix - restores order of heaps after ordering keys of i have changed
+func (p *SubPool) Fix(i *MetaTx) {
+	heap.Fix(p.best, i.bestIndex)
+	heap.Fix(p.worst, i.worstIndex)
+}
 func (p *SubPool) UnsafeAdd(i *MetaTx, subPoolType SubPoolType) {
 	i.currentSubPool = subPoolType
 	p.worst.Push(i)
 	p.best.Push(i)
+	p.slots += slotsOf(i)
+}
+
+// slotsOf - local transactions don't occupy slots of sub-pools
+func slotsOf(i *MetaTx) int {
+	if i.SubPool&IsLocal != 0 {
+		return 0
+	}
+	return txSlots(i.Tx)
 }
 func (p *SubPool) DebugPrint() {
 	for i, it := range *p.best {
@@ -645,18 +1555,29 @@ func (p *SubPool) DebugPrint() {
 
 type BestQueue []*MetaTx
 
+// Less - is mt less attractive than `than`: compares SubPool markers, then effective tip, then prefers local
+// transactions. IsLocal bit is not compared with the rest of the marker: it's the only bit which isn't inherited
+// by next nonces of the sender, so it could put transaction ahead of previous one. Transactions which can't pay
+// block base fee are compared by minFeeCap: same order as their negative effective tip, but it doesn't depend
+// on block base fee
 func (mt *MetaTx) Less(than *MetaTx) bool {
-	if mt.SubPool != than.SubPool {
-		return mt.SubPool < than.SubPool
+	if mt.SubPool&^IsLocal != than.SubPool&^IsLocal {
+		return mt.SubPool&^IsLocal < than.SubPool&^IsLocal
+	}
+	if mt.SubPool&EnoughFeeCapBlock == 0 && mt.minFeeCap != than.minFeeCap {
+		return mt.minFeeCap < than.minFeeCap
+	}
+	if mt.effectiveTip != than.effectiveTip {
+		return mt.effectiveTip < than.effectiveTip
 	}
 	// means that strict nonce ordering of transactions from the same sender must be observed.
-	if mt.Tx.senderID != than.Tx.senderID {
-		return mt.Tx.senderID < than.Tx.senderID
+	if mt.Tx.senderID == than.Tx.senderID {
+		return mt.Tx.nonce > than.Tx.nonce
 	}
-	if mt.Tx.nonce != than.Tx.nonce {
-		return mt.Tx.nonce < than.Tx.nonce
+	if mt.SubPool&IsLocal != than.SubPool&IsLocal {
+		return mt.SubPool&IsLocal == 0
 	}
-	return false
+	return mt.Tx.senderID < than.Tx.senderID
 }
 
 func (p BestQueue) Len() int           { return len(p) }
@@ -717,23 +1638,44 @@ func (p *WorstQueue) Pop() interface{} {
 //      - all local pooled byHash to random peers periodically
 // promote/demote transactions
 // reorgs
-func BroadcastLoop(ctx context.Context, p *TxPool, newTxs chan Hashes, send *Send, timings Timings) {
-	propagateAllNewTxsEvery := time.NewTicker(timings.propagateAllNewTxsEvery)
-	defer propagateAllNewTxsEvery.Stop()
-
-	syncToNewPeersEvery := time.NewTicker(timings.syncToNewPeersEvery)
+// eviction of expired transactions (see TxPoolConfig.QueuedLifetime)
+// also feeds subscribers of new transactions (newTxsStreams can be nil)
+// and periodically writes the pool to db (can be nil)
+func BroadcastLoop(ctx context.Context, db kv.RwDB, p *TxPool, newTxs chan Hashes, send *Send, newTxsStreams *NewTxsStreams, timings Timings) {
+	commitEvery := time.NewTicker(timings.CommitEvery)
+	defer commitEvery.Stop()
+
+	syncToNewPeersEvery := time.NewTicker(timings.SyncToNewPeersEvery)
 	defer syncToNewPeersEvery.Stop()
 
-	broadcastLocalTransactionsEvery := time.NewTicker(timings.broadcastLocalTransactionsEvery)
+	broadcastLocalTransactionsEvery := time.NewTicker(timings.BroadcastLocalTransactionsEvery)
 	defer broadcastLocalTransactionsEvery.Stop()
 
+	var evictExpiredEvery <-chan time.Time // nil - eviction is disabled
+	if timings.EvictExpiredEvery > 0 {
+		ticker := time.NewTicker(timings.EvictExpiredEvery)
+		defer ticker.Stop()
+		evictExpiredEvery = ticker.C
+	}
+
 	localTxHashes := make([]byte, 0, 128)
 	remoteTxHashes := make([]byte, 0, 128)
 
 	for {
 		select {
 		case <-ctx.Done():
+			if db != nil {
+				if err := p.Flush(db); err != nil {
+					log.Warn("[txpool] flush", "err", err)
+				}
+			}
 			return
+		case <-commitEvery.C:
+			if db != nil {
+				if err := p.Flush(db); err != nil {
+					log.Warn("[txpool] flush", "err", err)
+				}
+			}
 		case h := <-newTxs:
 			// first broadcast all local txs to all peers, then non-local to random sqrt(peersAmount) peers
 			localTxHashes = localTxHashes[:0]
@@ -743,21 +1685,96 @@ func BroadcastLoop(ctx context.Context, p *TxPool, newTxs chan Hashes, send *Sen
 				if p.IdHashIsLocal(h.At(i)) {
 					localTxHashes = append(localTxHashes, h.At(i)...)
 				} else {
-					remoteTxHashes = append(localTxHashes, h.At(i)...)
+					remoteTxHashes = append(remoteTxHashes, h.At(i)...)
 				}
 			}
 
 			send.BroadcastLocalPooledTxs(localTxHashes)
 			send.BroadcastRemotePooledTxs(remoteTxHashes)
+
+			if newTxsStreams != nil {
+				rlps := make([][]byte, 0, h.Len())
+				for i := 0; i < h.Len(); i++ {
+					if rlp := p.GetRlp(h.At(i)); rlp != nil {
+						rlps = append(rlps, rlp)
+					}
+				}
+				if len(rlps) > 0 {
+					newTxsStreams.Broadcast(&txpool_proto.OnAddReply{RplTxs: rlps})
+				}
+			}
 		case <-syncToNewPeersEvery.C: // new peer
 			newPeers := p.recentlyConnectedPeers.GetAndClean()
 			if len(newPeers) == 0 {
 				continue
 			}
-			p.AppendAllHashes(remoteTxHashes[:0])
+			remoteTxHashes = p.AppendAllHashes(remoteTxHashes[:0])
 			send.PropagatePooledTxsToPeersList(newPeers, remoteTxHashes)
+		case now := <-broadcastLocalTransactionsEvery.C:
+			if hashes := p.localsToRebroadcast(now, timings.BroadcastLocalTransactionsEvery, localRebroadcastLimit); len(hashes) > 0 {
+				send.RebroadcastLocalPooledTxs(hashes)
+			}
+		case now := <-evictExpiredEvery:
+			p.evictExpired(now)
+		}
+	}
+}
+
+const (
+	// maxLocalRebroadcastInterval - limit of backoff between re-announcements of same local transaction
+	maxLocalRebroadcastInterval = time.Hour
+	// localRebroadcastLimit - limit of re-announced hashes per tick, in bytes. Transactions which don't fit are
+	// re-announced on next ticks
+	localRebroadcastLimit = p2pTxPacketLimit
+)
+
+// localsToRebroadcast - hashes of local transactions from pending and baseFee sub-pools, which are due to be
+// re-announced at now, at most limit bytes. Transaction is scheduled first time interval after it's seen here
+// (it was announced when added to the pool), after every re-announcement its interval doubles
+func (p *TxPool) localsToRebroadcast(now time.Time, interval time.Duration, limit int) (hashes Hashes) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, sub := range []*SubPool{p.pending, p.baseFee} {
+		for _, mt := range *sub.best {
+			if mt.SubPool&IsLocal == 0 || mt.private {
+				continue
+			}
+			if mt.rebroadcastAt.IsZero() {
+				mt.rebroadcastAt, mt.rebroadcastInterval = now.Add(interval), interval
+				continue
+			}
+			if now.Before(mt.rebroadcastAt) || len(hashes)+32 > limit {
+				continue
+			}
+			hashes = append(hashes, mt.Tx.idHash[:]...)
+			mt.rebroadcastInterval *= 2
+			if mt.rebroadcastInterval > maxLocalRebroadcastInterval {
+				mt.rebroadcastInterval = maxLocalRebroadcastInterval
+			}
+			mt.rebroadcastAt = now.Add(mt.rebroadcastInterval)
+		}
+	}
+	return hashes
+}
+
+// evictExpired - removes non-local transactions of queued and baseFee sub-pools, which arrived more than
+// TxPoolConfig.QueuedLifetime before now
+func (p *TxPool) evictExpired(now time.Time) {
+	if p.cfg.QueuedLifetime == 0 {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	var expired []*MetaTx
+	for _, sub := range []*SubPool{p.baseFee, p.queued} {
+		for _, mt := range *sub.best {
+			if mt.SubPool&IsLocal == 0 && now.Sub(mt.timestamp) >= p.cfg.QueuedLifetime {
+				expired = append(expired, mt)
+			}
 		}
 	}
+	p.discardLocked(expired, LifetimeExpired)
+	p.gcSenders()
 }
 
 // recentlyConnectedPeers does buffer IDs of recently connected good peers
@@ -0,0 +1,359 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// Keys of kv.PoolInfo table
+var (
+	poolLastSeenBlockKey   = []byte("lastSeenBlock")
+	poolProtocolBaseFeeKey = []byte("protocolBaseFee")
+	poolBlockBaseFeeKey    = []byte("blockBaseFee")
+	poolBlockHeightKey     = []byte("blockHeight")
+)
+
+// privateTxValue - value of kv.PoolLocalTransaction for private transactions, other local ones have empty value
+var privateTxValue = []byte{1}
+
+// persistedState - what is already written to the db. Pool is written incrementally: Flush writes only
+// difference between this state and content of the pool
+type persistedState struct {
+	txs           map[string]dbTxFlags  // txn_hash => isLocal, private
+	senders       map[string]senderInfo // sender_address => nonce, balance (txNonce2Tx is not used)
+	localsHistory map[string]struct{}   // txn_hash
+}
+
+func newPersistedState() *persistedState {
+	return &persistedState{txs: map[string]dbTxFlags{}, senders: map[string]senderInfo{}, localsHistory: map[string]struct{}{}}
+}
+
+// dbChanges - changes of the pool, which are not written to the db yet. nil values mean deletion
+type dbChanges struct {
+	clear         bool // content of the tables is unknown (previous write failed) - they are re-written from scratch
+	txs           map[string]*dbTx
+	senders       map[string][]byte
+	localsHistory map[string][]byte // txn_hash => sender_address (empty if sender is unknown)
+	info          map[string][]byte
+}
+
+type dbTx struct {
+	rlp []byte
+	dbTxFlags
+}
+
+type dbTxFlags struct {
+	isLocal, private bool
+}
+
+// SetLastSeenBlock - remembers last block applied to the pool, it's persisted with the pool - to continue
+// stream of blocks from it after restart
+func (p *TxPool) SetLastSeenBlock(hash [32]byte) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.lastSeenBlock, p.hasLastSeenBlock = hash, true
+}
+
+func (p *TxPool) LastSeenBlock() (hash [32]byte, ok bool) {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+	return p.lastSeenBlock, p.hasLastSeenBlock
+}
+
+// Flush - writes changes of the pool since previous Flush (or FromDB) to the db with kv.TxPoolTables
+func (p *TxPool) Flush(db kv.RwDB) error {
+	p.lock.Lock()
+	changes := p.collectDBChanges()
+	p.lock.Unlock()
+
+	if err := db.Update(context.Background(), changes.write); err != nil {
+		p.lock.Lock()
+		p.persisted = nil
+		p.lock.Unlock()
+		return fmt.Errorf("flushing txpool: %w", err)
+	}
+	return nil
+}
+
+// collectDBChanges - compares content of the pool with persisted one. Must be called under lock
+func (p *TxPool) collectDBChanges() *dbChanges {
+	changes := &dbChanges{txs: map[string]*dbTx{}, senders: map[string][]byte{}, localsHistory: map[string][]byte{}, info: map[string][]byte{}}
+	if p.persisted == nil {
+		changes.clear = true
+		p.persisted = newPersistedState()
+	}
+	persisted := p.persisted
+
+	for hash, mt := range p.byHash {
+		flags := dbTxFlags{isLocal: mt.SubPool&IsLocal != 0, private: mt.private}
+		if prev, ok := persisted.txs[hash]; ok && prev == flags {
+			continue
+		}
+		changes.txs[hash] = &dbTx{rlp: mt.Tx.rlp, dbTxFlags: flags}
+		persisted.txs[hash] = flags
+	}
+	for hash := range persisted.txs {
+		if _, ok := p.byHash[hash]; !ok {
+			changes.txs[hash] = nil
+			delete(persisted.txs, hash)
+		}
+	}
+
+	for id, addr := range p.senderIDs.addrs {
+		info, ok := p.senderInfo[id]
+		if !ok {
+			continue
+		}
+		if prev, ok := persisted.senders[addr]; ok && prev.nonce == info.nonce && prev.balance.Eq(&info.balance) {
+			continue
+		}
+		changes.senders[addr] = encodeSenderCache(info.nonce, &info.balance)
+		persisted.senders[addr] = senderInfo{nonce: info.nonce, balance: info.balance}
+	}
+	for addr := range persisted.senders {
+		if id, ok := p.senderIDs.id(addr); ok {
+			if _, ok = p.senderInfo[id]; ok {
+				continue
+			}
+		}
+		changes.senders[addr] = nil
+		delete(persisted.senders, addr)
+	}
+
+	recentLocals := map[string]struct{}{}
+	for _, k := range p.localsHistory.Keys() {
+		hash := k.([32]byte)
+		recentLocals[string(hash[:])] = struct{}{}
+		if _, ok := persisted.localsHistory[string(hash[:])]; !ok {
+			sender := []byte{}
+			if senderID, ok := p.localsHistory.Peek(k); ok {
+				if id, ok := senderID.(uint64); ok {
+					sender = append(sender, p.senderIDs.addr(id)...)
+				}
+			}
+			changes.localsHistory[string(hash[:])] = sender
+			persisted.localsHistory[string(hash[:])] = struct{}{}
+		}
+	}
+	for hash := range persisted.localsHistory {
+		if _, ok := recentLocals[hash]; !ok {
+			changes.localsHistory[hash] = nil
+			delete(persisted.localsHistory, hash)
+		}
+	}
+
+	if p.hasLastSeenBlock {
+		changes.info[string(poolLastSeenBlockKey)] = copyBytes(p.lastSeenBlock[:])
+	}
+	var protocolBaseFee, blockBaseFee, blockHeight [8]byte
+	binary.BigEndian.PutUint64(protocolBaseFee[:], p.protocolBaseFee.Load())
+	binary.BigEndian.PutUint64(blockBaseFee[:], p.blockBaseFee.Load())
+	binary.BigEndian.PutUint64(blockHeight[:], p.blockHeight.Load())
+	changes.info[string(poolProtocolBaseFeeKey)] = protocolBaseFee[:]
+	changes.info[string(poolBlockBaseFeeKey)] = blockBaseFee[:]
+	changes.info[string(poolBlockHeightKey)] = blockHeight[:]
+	return changes
+}
+
+func (c *dbChanges) write(tx kv.RwTx) error {
+	if c.clear {
+		for _, table := range kv.TxPoolTables {
+			if err := tx.ClearBucket(table); err != nil {
+				return err
+			}
+		}
+	}
+	for hash, t := range c.txs {
+		if t == nil {
+			if err := tx.Delete(kv.PoolTransaction, []byte(hash), nil); err != nil {
+				return err
+			}
+			if err := tx.Delete(kv.PoolLocalTransaction, []byte(hash), nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Put(kv.PoolTransaction, []byte(hash), t.rlp); err != nil {
+			return err
+		}
+		if t.isLocal {
+			var v []byte
+			if t.private {
+				v = privateTxValue
+			}
+			if err := tx.Put(kv.PoolLocalTransaction, []byte(hash), v); err != nil {
+				return err
+			}
+		} else if err := tx.Delete(kv.PoolLocalTransaction, []byte(hash), nil); err != nil {
+			return err
+		}
+	}
+	for addr, v := range c.senders {
+		if v == nil {
+			if err := tx.Delete(kv.PoolSender, []byte(addr), nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Put(kv.PoolSender, []byte(addr), v); err != nil {
+			return err
+		}
+	}
+	for hash, sender := range c.localsHistory {
+		if sender == nil {
+			if err := tx.Delete(kv.RecentLocalTransaction, []byte(hash), nil); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := tx.Put(kv.RecentLocalTransaction, []byte(hash), sender); err != nil {
+			return err
+		}
+	}
+	for k, v := range c.info {
+		if err := tx.Put(kv.PoolInfo, []byte(k), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FromDB - restores the pool from the db with kv.TxPoolTables. Must be called before the pool is used.
+// Transactions are parsed again, ones which can't be parsed or added anymore are deleted from the db by next Flush
+func (p *TxPool) FromDB(tx kv.Tx) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	persisted := newPersistedState()
+
+	v, err := tx.GetOne(kv.PoolInfo, poolLastSeenBlockKey)
+	if err != nil {
+		return err
+	}
+	if len(v) == 32 {
+		copy(p.lastSeenBlock[:], v)
+		p.hasLastSeenBlock = true
+	}
+	if v, err = tx.GetOne(kv.PoolInfo, poolProtocolBaseFeeKey); err != nil {
+		return err
+	}
+	if len(v) == 8 {
+		p.protocolBaseFee.Store(binary.BigEndian.Uint64(v))
+	}
+	if v, err = tx.GetOne(kv.PoolInfo, poolBlockBaseFeeKey); err != nil {
+		return err
+	}
+	if len(v) == 8 {
+		p.blockBaseFee.Store(binary.BigEndian.Uint64(v))
+	}
+	// height is needed to validate types of persisted transactions
+	if v, err = tx.GetOne(kv.PoolInfo, poolBlockHeightKey); err != nil {
+		return err
+	}
+	if len(v) == 8 {
+		p.blockHeight.Store(binary.BigEndian.Uint64(v))
+	}
+
+	if err = tx.ForEach(kv.PoolSender, nil, func(k, v []byte) error {
+		nonce, balance, err := decodeSenderCache(v)
+		if err != nil {
+			return fmt.Errorf("sender %x: %w", k, err)
+		}
+		p.senderInfo[p.senderIDs.getOrCreateID(string(k))] = newSenderInfo(nonce, balance)
+		persisted.senders[string(k)] = senderInfo{nonce: nonce, balance: balance}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// senders of history are resolved to ids after senders cache is loaded: their state is kept (see gcSenders)
+	if err = tx.ForEach(kv.RecentLocalTransaction, nil, func(k, v []byte) error {
+		var hash [32]byte
+		copy(hash[:], k)
+		if len(v) == 20 {
+			p.localsHistory.Add(hash, p.senderIDs.getOrCreateID(string(v)))
+		} else {
+			p.localsHistory.Add(hash, struct{}{}) // sender is unknown, history only restores isLocal flag
+		}
+		persisted.localsHistory[string(k)] = struct{}{}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	locals := map[string]dbTxFlags{}
+	if err = tx.ForEach(kv.PoolLocalTransaction, nil, func(k, v []byte) error {
+		locals[string(k)] = dbTxFlags{isLocal: true, private: bytes.Equal(v, privateTxValue)}
+		return nil
+	}); err != nil {
+		return err
+	}
+	var txs TxSlots
+	parseCtx := NewTxParseContext()
+	if err = tx.ForEach(kv.PoolTransaction, nil, func(k, v []byte) error {
+		flags := locals[string(k)]
+		persisted.txs[string(k)] = flags
+		slot, sender, err := parseCtx.ParseSingleTransaction(copyBytes(v))
+		if err != nil {
+			log.Warn("[txpool] parsing persisted transaction", "hash", fmt.Sprintf("%x", k), "err", err)
+			return nil
+		}
+		if flags.private {
+			txs.AppendPrivate(slot, sender[:])
+		} else {
+			txs.Append(slot, sender[:], flags.isLocal)
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	p.persisted = persisted
+
+	setTxSenderID(p.senderIDs, txs)
+	if _, err = p.addTxsLocked(p.holdUnknownSenders(txs)); err != nil {
+		return err
+	}
+	return nil
+}
+
+// encodeSenderCache - value of kv.PoolSender: nonce (8 bytes) + balance (32 bytes), big-endian
+func encodeSenderCache(nonce uint64, balance *uint256.Int) []byte {
+	v := make([]byte, 40)
+	binary.BigEndian.PutUint64(v, nonce)
+	balance.WriteToSlice(v[8:])
+	return v
+}
+
+func decodeSenderCache(v []byte) (nonce uint64, balance uint256.Int, err error) {
+	if len(v) != 40 {
+		return 0, balance, fmt.Errorf("unexpected length of sender cache: %d", len(v))
+	}
+	balance.SetBytes(v[8:])
+	return binary.BigEndian.Uint64(v), balance, nil
+}
+
+// copyBytes - values read from the db are valid only until end of transaction, pool keeps them longer
+func copyBytes(b []byte) []byte {
+	return append([]byte{}, b...)
+}
@@ -0,0 +1,115 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolDB(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+	db := memdb.NewTestPoolDB(t)
+
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(5, 1))
+	local := parseTxSlots(t, true, txParseTests[0].payloadStr)
+	remote := parseTxSlots(t, false, txParseTests[3].payloadStr)
+	_, err := pool.AddLocals(ctx, local)
+	require.NoError(err)
+	_, err = pool.AddLocals(ctx, remote) // isLocal comes from slots
+	require.NoError(err)
+	parsed := parseTxSlots(t, true, txParseTests[1].payloadStr)
+	var private TxSlots
+	private.AppendPrivate(parsed.txs[0], parsed.senders)
+	reasons, err := pool.AddLocals(ctx, private)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success}, reasons)
+	// mined local transaction of sender without transactions in the pool
+	recentLocal, historySender := [32]byte{7}, string([]byte{9, 19: 0})
+	pool.lock.Lock()
+	historyID := pool.senderIDs.getOrCreateID(historySender)
+	pool.senderInfo[historyID] = newSenderInfo(3, testBalance)
+	pool.localsHistory.Add(recentLocal, historyID)
+	pool.lock.Unlock()
+	pool.SetLastSeenBlock([32]byte{1})
+	require.NoError(pool.Flush(db))
+
+	// restore without sender state provider - state of senders is in the db
+	restored, err := New(make(chan Hashes, 10), DefaultConfig, nil)
+	require.NoError(err)
+	require.NoError(db.View(ctx, restored.FromDB))
+	require.True(restored.IdHashIsLocal(local.txs[0].idHash[:]))
+	require.True(restored.IdHashKnown(remote.txs[0].idHash[:]))
+	require.False(restored.IdHashIsLocal(remote.txs[0].idHash[:]))
+	require.True(restored.IdHashIsLocal(private.txs[0].idHash[:]))
+	require.Nil(restored.GetRlp(private.txs[0].idHash[:]))
+	require.NotNil(restored.GetRlpWithPrivate(private.txs[0].idHash[:]))
+	require.True(restored.localsHistory.Contains(recentLocal))
+	// restored history keeps state of its sender
+	restored.lock.Lock()
+	id, ok := restored.senderIDs.id(historySender)
+	require.True(ok)
+	senderID, _ := restored.localsHistory.Peek(recentLocal)
+	require.Equal(id, senderID)
+	restored.gcCandidates[id] = struct{}{}
+	restored.gcSenders()
+	require.Contains(restored.senderInfo, id)
+	restored.lock.Unlock()
+	lastSeenBlock, ok := restored.LastSeenBlock()
+	require.True(ok)
+	require.Equal([32]byte{1}, lastSeenBlock)
+	require.Equal(uint64(1), restored.protocolBaseFee.Load())
+	require.Equal(uint64(5), restored.blockHeight.Load())
+
+	// changes are written incrementally: mined transaction is deleted, sender state is updated. Sender without
+	// transactions is kept while its mined local transaction is in localsHistory
+	require.NoError(restored.OnNewBlock(map[string]senderInfo{
+		string(decodeHex(txParseTests[0].senderStr)): {nonce: 1, balance: testBalance},
+		string(decodeHex(txParseTests[3].senderStr)): {nonce: 0, balance: *uint256.NewInt(1)},
+	}, TxSlots{}, TxSlots{}, headerWithBaseFee(6, 1), 1))
+	require.NoError(restored.Flush(db))
+	require.NoError(db.View(ctx, func(tx kv.Tx) error {
+		v, err := tx.GetOne(kv.PoolTransaction, local.txs[0].idHash[:])
+		require.NoError(err)
+		require.Nil(v)
+		v, err = tx.GetOne(kv.PoolLocalTransaction, local.txs[0].idHash[:])
+		require.NoError(err)
+		require.Nil(v)
+		v, err = tx.GetOne(kv.RecentLocalTransaction, local.txs[0].idHash[:])
+		require.NoError(err)
+		require.Equal(decodeHex(txParseTests[0].senderStr), v)
+		v, err = tx.GetOne(kv.RecentLocalTransaction, recentLocal[:])
+		require.NoError(err)
+		require.Equal([]byte(historySender), v)
+		v, err = tx.GetOne(kv.PoolTransaction, remote.txs[0].idHash[:])
+		require.NoError(err)
+		require.Equal(decodeHex(txParseTests[3].payloadStr), v)
+		v, err = tx.GetOne(kv.PoolSender, decodeHex(txParseTests[0].senderStr))
+		require.NoError(err)
+		require.Equal(encodeSenderCache(1, &testBalance), v)
+		v, err = tx.GetOne(kv.PoolSender, decodeHex(txParseTests[3].senderStr))
+		require.NoError(err)
+		require.Equal(encodeSenderCache(0, uint256.NewInt(1)), v)
+		return nil
+	}))
+}
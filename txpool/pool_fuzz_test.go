@@ -103,7 +103,7 @@ func u256Slice(in []byte) ([]uint256.Int, bool) {
 	return res, true
 }
 
-func poolsFromFuzzBytes(rawTxNonce, rawValues, rawTips, rawSender, rawSenderNonce, rawSenderBalance []byte) (sendersInfo map[uint64]*senderInfo, senderIDs map[string]uint64, txs TxSlots, ok bool) {
+func poolsFromFuzzBytes(rawTxNonce, rawValues, rawTips, rawSender, rawSenderNonce, rawSenderBalance []byte) (sendersInfo map[uint64]*senderInfo, senderIDs *sendersRegistry, txs TxSlots, ok bool) {
 	if len(rawTxNonce) < 8 || len(rawValues) < 32 || len(rawTips) < 8 || len(rawSender) < 20 || len(rawSenderNonce) < 8 || len(rawSenderBalance) < 32 {
 		return nil, nil, txs, false
 	}
@@ -147,13 +147,12 @@ func poolsFromFuzzBytes(rawTxNonce, rawValues, rawTips, rawSender, rawSenderNonc
 	}
 
 	sendersInfo = map[uint64]*senderInfo{}
-	senderIDs = map[string]uint64{}
+	senderIDs = newSendersRegistry()
 	for i := 0; i < len(senderNonce); i++ {
-		senderID := uint64(i + 1) //non-zero expected
+		senderID := senderIDs.getOrCreateID(string(rawSender[i*20 : (i+1)*20]))
 		sendersInfo[senderID] = newSenderInfo(senderNonce[i], senderBalance[i])
-		senderIDs[string(rawSender[i*20:(i+1)*20])] = senderID
 	}
-	sendersAmount := len(sendersInfo)
+	sendersAmount := len(senderNonce)
 	for i := range txNonce {
 		txs.txs = append(txs.txs, &TxSlot{
 			nonce: txNonce[i],
@@ -163,6 +162,7 @@ func poolsFromFuzzBytes(rawTxNonce, rawValues, rawTips, rawSender, rawSenderNonc
 		senderN := i % sendersAmount
 		txs.senders = append(txs.senders, rawSender[senderN*20:(senderN+1)*20]...)
 		txs.isLocal = append(txs.isLocal, false)
+		txs.private = append(txs.private, false)
 	}
 
 	return sendersInfo, senderIDs, txs, true
@@ -180,18 +180,22 @@ func splitDataset(in TxSlots) (TxSlots, TxSlots, TxSlots, TxSlots) {
 
 	p1.txs = in.txs[:l]
 	p1.isLocal = in.isLocal[:l]
+	p1.private = in.private[:l]
 	p1.senders = in.senders[:l*20]
 
 	p2.txs = in.txs[l : 2*l]
 	p2.isLocal = in.isLocal[l : 2*l]
+	p2.private = in.private[l : 2*l]
 	p2.senders = in.senders[l*20 : 2*l*20]
 
 	p3.txs = in.txs[2*l : 3*l]
 	p3.isLocal = in.isLocal[2*l : 3*l]
+	p3.private = in.private[2*l : 3*l]
 	p3.senders = in.senders[2*l*20 : 3*l*20]
 
 	p4.txs = in.txs[3*l : 4*l]
 	p4.isLocal = in.isLocal[3*l : 4*l]
+	p4.private = in.private[3*l : 4*l]
 	p4.senders = in.senders[3*l*20 : 4*l*20]
 
 	return p1, p2, p3, p4
@@ -219,14 +223,15 @@ func FuzzOnNewBlocks6(f *testing.F) {
 		assert := assert.New(t)
 
 		ch := make(chan Hashes, 100)
-		pool := New(ch)
+		pool, err := New(ch, DefaultConfig, nil)
+		assert.NoError(err)
 		pool.senderInfo = senders
 		pool.senderIDs = senderIDs
 		check := func(unwindTxs, minedTxs TxSlots) {
 			pending, baseFee, queued := pool.pending, pool.baseFee, pool.queued
 
 			best, worst := pending.Best(), pending.Worst()
-			assert.LessOrEqual(pending.Len(), PendingSubPoolLimit)
+			assert.LessOrEqual(pending.Slots(), DefaultConfig.PendingSubPoolLimit)
 			assert.False(worst != nil && best == nil)
 			assert.False(worst == nil && best != nil)
 			if worst != nil && worst.SubPool < 0b11110 {
@@ -264,7 +269,7 @@ func FuzzOnNewBlocks6(f *testing.F) {
 
 			assert.False(worst != nil && best == nil)
 			assert.False(worst == nil && best != nil)
-			assert.LessOrEqual(baseFee.Len(), BaseFeeSubPoolLimit)
+			assert.LessOrEqual(baseFee.Slots(), DefaultConfig.BaseFeeSubPoolLimit)
 			if worst != nil && worst.SubPool < 0b11100 {
 				t.Fatalf("baseFee worst too small %b", worst.SubPool)
 			}
@@ -286,7 +291,7 @@ func FuzzOnNewBlocks6(f *testing.F) {
 			})
 
 			best, worst = queued.Best(), queued.Worst()
-			assert.LessOrEqual(queued.Len(), QueuedSubPoolLimit)
+			assert.LessOrEqual(queued.Slots(), DefaultConfig.QueuedSubPoolLimit)
 			assert.False(worst != nil && best == nil)
 			assert.False(worst == nil && best != nil)
 			if worst != nil && worst.SubPool < 0b10000 {
@@ -333,7 +338,7 @@ func FuzzOnNewBlocks6(f *testing.F) {
 
 		// go to first fork
 		unwindTxs, minedTxs1, p2pReceived, minedTxs2 := splitDataset(txs)
-		err := pool.OnNewBlock(unwindTxs, minedTxs1, protocolBaseFee, blockBaseFee)
+		err := pool.OnNewBlock(nil, unwindTxs, minedTxs1, headerWithBaseFee(0, blockBaseFee), protocolBaseFee)
 		assert.NoError(err)
 		check(unwindTxs, minedTxs1)
 		select {
@@ -346,7 +351,7 @@ func FuzzOnNewBlocks6(f *testing.F) {
 		//assert.Equal(len(unwindTxs.txs), newHashes.Len())
 
 		// unwind everything and switch to new fork (need unwind mined now)
-		err = pool.OnNewBlock(minedTxs1, minedTxs2, protocolBaseFee, blockBaseFee)
+		err = pool.OnNewBlock(nil, minedTxs1, minedTxs2, headerWithBaseFee(0, blockBaseFee), protocolBaseFee)
 		assert.NoError(err)
 		check(minedTxs1, minedTxs2)
 		select {
@@ -16,6 +16,359 @@
 
 package txpool
 
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/direct"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+type senderStateProviderMock func(ctx context.Context, addr [20]byte) (uint64, uint256.Int, error)
+
+func (f senderStateProviderMock) SenderState(ctx context.Context, addr [20]byte) (uint64, uint256.Int, error) {
+	return f(ctx, addr)
+}
+
+// testBalance - balance of senders of newTestPool, enough for any test transaction
+var testBalance = *uint256.NewInt(1_000_000_000_000_000_000)
+
+// newTestPool - pool on top of head. Senders have zero nonce and testBalance, unless senderState is given
+func newTestPool(t *testing.T, cfg TxPoolConfig, senderState SenderStateProvider, head BlockHeader) *TxPool {
+	if senderState == nil {
+		senderState = senderStateProviderMock(func(ctx context.Context, addr [20]byte) (uint64, uint256.Int, error) {
+			return 0, testBalance, nil
+		})
+	}
+	pool, err := New(make(chan Hashes, 10), cfg, senderState)
+	require.NoError(t, err)
+	require.NoError(t, pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, head, 1))
+	return pool
+}
+
+func parseTxSlots(t *testing.T, isLocal bool, payloads ...string) (txs TxSlots) {
+	ctx := NewTxParseContext()
+	for _, payload := range payloads {
+		slot, sender, _, err := ctx.ParseTransaction(decodeHex(payload), 0)
+		require.NoError(t, err)
+		txs.Append(slot, sender[:], isLocal)
+	}
+	return txs
+}
+
+// inPool - transaction is in sub-pools, not only held back waiting for state of its sender
+func inPool(pool *TxPool, hash []byte) bool {
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+	_, ok := pool.byHash[string(hash)]
+	return ok
+}
+
+func TestOnNewTxsLoadsSenderState(t *testing.T) {
+	require := require.New(t)
+	release := make(chan struct{})
+	requested := make(chan [20]byte, 10)
+	cfg := DefaultConfig
+	cfg.SenderStateLoaders, cfg.WaitingTxsLimit = 1, 2
+	pool := newTestPool(t, cfg, senderStateProviderMock(func(ctx context.Context, addr [20]byte) (uint64, uint256.Int, error) {
+		requested <- addr
+		<-release
+		return 0, testBalance, nil
+	}), headerWithBaseFee(0, 1))
+
+	txs := parseTxSlots(t, false, txParseTests[0].payloadStr, txParseTests[1].payloadStr, txParseTests[3].payloadStr)
+	hash := func(i int) []byte { return txs.txs[i].idHash[:] }
+	sender := func(i int) (addr [20]byte) { copy(addr[:], txs.senders[i*20:]); return addr }
+	require.NoError(pool.OnNewTxs(parseTxSlots(t, false, txParseTests[0].payloadStr)))
+	require.Equal(sender(0), <-requested)
+	// transaction is held back until sender state arrives, it's known to the pool meanwhile
+	require.False(inPool(pool, hash(0)))
+	require.True(pool.IdHashKnown(hash(0)))
+
+	// held transaction is not held twice, state of next sender waits for the only loader, transactions over the
+	// limit are dropped
+	require.NoError(pool.OnNewTxs(txs))
+	require.Empty(requested)
+	require.False(pool.IdHashKnown(hash(2)))
+	pool.lock.RLock()
+	require.Equal(1, len(pool.waitingSenders[txs.txs[0].senderID].txs))
+	require.Equal(2, len(pool.waitingHashes))
+	pool.lock.RUnlock()
+
+	close(release)
+	require.Equal(sender(1), <-requested)
+	require.Eventually(func() bool { return inPool(pool, hash(0)) && inPool(pool, hash(1)) }, time.Second, time.Millisecond)
+	require.False(pool.IdHashKnown(hash(2)))
+	require.Empty(requested)
+
+	pool.lock.RLock()
+	defer pool.lock.RUnlock()
+	require.Empty(pool.waitingSenders)
+	require.Empty(pool.waitingHashes)
+	require.Zero(pool.loaders)
+	require.Equal(PendingSubPool, pool.byHash[string(hash(0))].currentSubPool)
+}
+
+func TestOnNewTxsWithoutSenderState(t *testing.T) {
+	pool, err := New(make(chan Hashes, 10), DefaultConfig, nil)
+	require.NoError(t, err)
+	require.NoError(t, pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(0, 1), 1))
+	txs := parseTxSlots(t, false, txParseTests[0].payloadStr)
+	require.NoError(t, pool.OnNewTxs(txs))
+	require.False(t, pool.IdHashKnown(txs.txs[0].idHash[:]))
+	// mined transactions of unknown senders are ignored
+	require.NoError(t, pool.OnNewBlock(nil, TxSlots{}, txs, headerWithBaseFee(0, 1), 1))
+}
+
+func TestSubPoolsOrderByEffectiveTip(t *testing.T) {
+	require := require.New(t)
+	balance := *uint256.NewInt(1_000_000_000_000_000_000)
+	senders := map[uint64]*senderInfo{}
+	var txs TxSlots
+	for i, tx := range []struct {
+		senderID, nonce, tip, feeCap uint64
+		isLocal                      bool
+	}{
+		{senderID: 1, nonce: 0, tip: 1, feeCap: 100},
+		{senderID: 1, nonce: 1, tip: 50, feeCap: 100}, // can't be more attractive than previous nonce
+		{senderID: 2, nonce: 0, tip: 10, feeCap: 100},
+		{senderID: 3, nonce: 0, tip: 30, feeCap: 60},
+		{senderID: 4, nonce: 0, tip: 1, feeCap: 10},
+		{senderID: 4, nonce: 1, tip: 1, feeCap: 100}, // previous nonce doesn't cover block base fee
+		{senderID: 5, nonce: 0, tip: 20, feeCap: 100},
+		{senderID: 5, nonce: 1, tip: 20, feeCap: 100, isLocal: true}, // being local doesn't put it ahead of previous nonce
+	} {
+		senders[tx.senderID] = newSenderInfo(0, balance)
+		slot := &TxSlot{senderID: tx.senderID, nonce: tx.nonce, tip: tx.tip, feeCap: tx.feeCap, gas: 21000}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, make([]byte, 20), tx.isLocal)
+	}
+	pending, baseFee, queued := NewSubPool(), NewSubPool(), NewSubPool()
+	byHash := map[string]*MetaTx{}
+	localsHistory, _ := lru.New(1024)
+	_, err := onNewTxs(DefaultConfig, senders, txs, 1, 20, 1, pending, baseFee, queued, byHash, localsHistory, map[uint64]struct{}{}, nil)
+	require.NoError(err)
+
+	order := func(sub *SubPool) (ids [][2]uint64) {
+		sorted := append([]*MetaTx{}, *sub.best...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[j].Less(sorted[i]) })
+		for _, mt := range sorted {
+			ids = append(ids, [2]uint64{mt.Tx.senderID, mt.Tx.nonce})
+		}
+		return ids
+	}
+	require.Equal([][2]uint64{{3, 0}, {5, 0}, {5, 1}, {2, 0}, {1, 0}, {1, 1}}, order(pending))
+	require.Equal(uint64(3), pending.Best().Tx.senderID)
+	require.Equal([2]uint64{1, 1}, [2]uint64{pending.Worst().Tx.senderID, pending.Worst().Tx.nonce})
+	require.Equal(2, baseFee.Len())
+
+	// base fee grows - effective tip of sender 3 becomes min(30, 60-55) = 5
+	require.NoError(onNewBlock(DefaultConfig, senders, nil, TxSlots{}, nil, 1, 55, 1, true, pending, baseFee, queued, byHash, localsHistory, map[uint64]struct{}{}, nil))
+	require.Equal([][2]uint64{{5, 0}, {5, 1}, {2, 0}, {3, 0}, {1, 0}, {1, 1}}, order(pending))
+}
+
+func TestOnBaseFeeChange(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 20))
+	var txs TxSlots
+	for i, tx := range []struct{ tip, feeCap uint64 }{
+		{tip: 1, feeCap: 100},
+		{tip: 10, feeCap: 100},
+		{tip: 30, feeCap: 60}, // tip is limited by fee cap when base fee is above 30
+		{tip: 5, feeCap: 25},  // crosses the boundary when base fee is above 25
+		{tip: 2, feeCap: 18},  // in baseFee sub-pool until base fee is below 18
+	} {
+		slot := &TxSlot{tip: tx.tip, feeCap: tx.feeCap, gas: 21000}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, []byte{byte(i + 1), 19: 0}, true)
+	}
+	_, err := pool.AddLocals(context.Background(), txs)
+	require.NoError(err)
+	events, unsubscribe := pool.Subscribe(10)
+	defer unsubscribe()
+
+	// senders of transactions in pending sub-pool, from the best: heaps must agree with full sort
+	pendingOrder := func() (senders []byte) {
+		pool.lock.RLock()
+		defer pool.lock.RUnlock()
+		sorted := append([]*MetaTx{}, *pool.pending.best...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[j].Less(sorted[i]) })
+		require.Equal(sorted[0], pool.pending.Best())
+		require.Equal(sorted[len(sorted)-1], pool.pending.Worst())
+		for _, mt := range sorted {
+			senders = append(senders, mt.Tx.idHash[0])
+		}
+		return senders
+	}
+	newBaseFee := func(baseFee uint64) {
+		require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(0, baseFee), 1))
+		require.Equal(baseFee, pool.PendingBaseFee())
+	}
+	require.Equal([]byte{3, 2, 4, 1}, pendingOrder())
+	require.Equal(PoolStatus{Pending: 4, BaseFee: 1}, pool.Status())
+
+	newBaseFee(30)
+	require.Equal([]byte{3, 2, 1}, pendingOrder())
+	require.Equal(Event{Kind: TxDemoted, Hash: txs.txs[3].idHash, Sender: [20]byte{4}, From: PendingSubPool, To: BaseFeeSubPool}, <-events)
+
+	// effective tip of transaction 3 is min(30, 60-55). Senders are not recalculated: balance of sender 1 is
+	// changed behind the pool's back, it's noticed only when the sender is touched by a block
+	pool.lock.Lock()
+	pool.senderInfo[pool.byHash[string(txs.txs[0].idHash[:])].Tx.senderID].balance.Clear()
+	pool.lock.Unlock()
+	newBaseFee(55)
+	require.Equal([]byte{2, 3, 1}, pendingOrder())
+	require.Empty(events)
+
+	newBaseFee(15)
+	require.Equal([]byte{3, 2, 4, 5, 1}, pendingOrder())
+	require.Equal(PoolStatus{Pending: 5}, pool.Status())
+	promoted := map[byte]Event{}
+	for len(promoted) < 2 {
+		ev := <-events
+		promoted[ev.Hash[0]] = ev
+	}
+	require.Equal(TxPromoted, promoted[4].Kind)
+	require.Equal(TxPromoted, promoted[5].Kind)
+}
+
+func TestReplaceByFee(t *testing.T) {
+	require := require.New(t)
+	senders := map[uint64]*senderInfo{1: newSenderInfo(0, *uint256.NewInt(1_000_000_000_000_000_000))}
+	pending, baseFee, queued := NewSubPool(), NewSubPool(), NewSubPool()
+	byHash := map[string]*MetaTx{}
+	localsHistory, _ := lru.New(1024)
+	add := func(hash byte, tip, feeCap uint64) DiscardReason {
+		slot := &TxSlot{senderID: 1, nonce: 0, tip: tip, feeCap: feeCap, gas: 21000}
+		slot.idHash[0] = hash
+		var txs TxSlots
+		txs.Append(slot, make([]byte, 20), false)
+		reasons, err := onNewTxs(DefaultConfig, senders, txs, 1, 10, 1, pending, baseFee, queued, byHash, localsHistory, map[uint64]struct{}{}, nil)
+		require.NoError(err)
+		return reasons[0]
+	}
+	require.Equal(Success, add(1, 10, 100))
+	require.Equal(ReplaceUnderpriced, add(2, 10, 200))  // same tip
+	require.Equal(ReplaceUnderpriced, add(3, 10, 109))  // bump of feeCap is less than 10%
+	require.Equal(ReplaceUnderpriced, add(4, 100, 109)) // bump of tip is enough, of feeCap isn't
+	require.Len(byHash, 1)
+	require.Equal(1, pending.Len())
+
+	require.Equal(Success, add(5, 11, 110))
+	require.Len(byHash, 1)
+	require.Contains(byHash, string([]byte{5, 31: 0}))
+	require.Equal(1, pending.Len())
+	require.Equal(byte(5), pending.Best().Tx.idHash[0])
+	require.Equal(0, baseFee.Len()+queued.Len())
+	require.Equal(1, senders[1].txNonce2Tx.Len())
+}
+
+func TestValidateTx(t *testing.T) {
+	require := require.New(t)
+	senders := map[uint64]*senderInfo{1: newSenderInfo(0, *uint256.NewInt(1_000_000_000_000_000_000))}
+	pending, baseFee, queued := NewSubPool(), NewSubPool(), NewSubPool()
+	byHash := map[string]*MetaTx{}
+	localsHistory, _ := lru.New(1024)
+	var txs TxSlots
+	for i, tx := range []*TxSlot{
+		{nonce: 0, tip: 1, feeCap: 10, gas: 21000, rlp: make([]byte, DefaultConfig.MaxTxSize+1)},
+		{nonce: 0, tip: 1, feeCap: 10, gas: DefaultConfig.BlockGasLimit + 1},
+		{nonce: 0, tip: 1, feeCap: 10, gas: 53000 + 4 + 16 - 1, creation: true, dataLen: 2, dataNonZero: 1},
+		{nonce: 0, tip: 1, feeCap: 10, gas: 21000 + 2400 + 2*1900 - 1, alAddrCount: 1, alStorCount: 2},
+		{nonce: 0, tip: 11, feeCap: 10, gas: 21000},
+		{nonce: 0, tip: 1, feeCap: 10, gas: 53000 + 4 + 16, creation: true, dataLen: 2, dataNonZero: 1},
+	} {
+		tx.senderID = 1
+		tx.idHash[0] = byte(i + 1)
+		txs.Append(tx, make([]byte, 20), false)
+	}
+	reasons, err := onNewTxs(DefaultConfig, senders, txs, 1, 1, 1, pending, baseFee, queued, byHash, localsHistory, map[uint64]struct{}{}, nil)
+	require.NoError(err)
+	require.Equal([]DiscardReason{OversizedData, GasLimitTooHigh, IntrinsicGas, IntrinsicGas, TipAboveFeeCap, Success}, reasons)
+	require.Len(byHash, 1)
+}
+
+func TestSlotLimits(t *testing.T) {
+	require := require.New(t)
+	cfg := DefaultConfig
+	cfg.AccountSlots, cfg.QueuedSubPoolLimit = 2, 3
+	balance := *uint256.NewInt(1_000_000_000_000_000_000)
+	senders := map[uint64]*senderInfo{1: newSenderInfo(0, balance), 2: newSenderInfo(0, balance), 3: newSenderInfo(0, balance)}
+	pending, baseFee, queued := NewSubPool(), NewSubPool(), NewSubPool()
+	byHash := map[string]*MetaTx{}
+	localsHistory, _ := lru.New(1024)
+	var txs TxSlots
+	// all transactions have nonce gap, so they go to queued sub-pool
+	for i, tx := range []struct {
+		senderID, nonce, tip uint64
+		size                 int
+		isLocal              bool
+	}{
+		{senderID: 1, nonce: 1, tip: 5},
+		{senderID: 1, nonce: 2, tip: 5},
+		{senderID: 1, nonce: 3, tip: 5},                // sender already has AccountSlots
+		{senderID: 1, nonce: 4, tip: 5, isLocal: true}, // local transactions don't occupy slots
+		{senderID: 2, nonce: 1, tip: 1},
+		{senderID: 3, nonce: 1, tip: 20, size: 40 * 1024}, // takes 2 slots - queued sub-pool overflows by 2 slots
+	} {
+		slot := &TxSlot{senderID: tx.senderID, nonce: tx.nonce, tip: tx.tip, feeCap: 100, gas: 21000, rlp: make([]byte, tx.size)}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, make([]byte, 20), tx.isLocal)
+	}
+	reasons, err := onNewTxs(cfg, senders, txs, 1, 10, 1, pending, baseFee, queued, byHash, localsHistory, map[uint64]struct{}{}, nil)
+	require.NoError(err)
+	// the worst transactions are evicted first, whichever sender they have
+	require.Equal([]DiscardReason{Success, QueuedPoolOverflow, SenderPoolOverflow, Success, QueuedPoolOverflow, Success}, reasons)
+	require.Equal(3, queued.Slots())
+	require.Equal(3, queued.Len())
+	require.Len(byHash, 3)
+}
+
+func TestEvictWorstKeepsLocals(t *testing.T) {
+	require := require.New(t)
+	cfg := DefaultConfig
+	cfg.QueuedSubPoolLimit = 1
+	pool := newTestPool(t, cfg, nil, headerWithBaseFee(0, 10))
+	// all transactions have nonce gap; local one can't pay for its value, so it's the worst in queued sub-pool
+	var txs TxSlots
+	for i, tx := range []struct {
+		sender  byte
+		isLocal bool
+	}{{sender: 1, isLocal: true}, {sender: 2}, {sender: 3}} {
+		slot := &TxSlot{nonce: 1, tip: 1, feeCap: 100, gas: 21000}
+		if tx.isLocal {
+			slot.value.Add(&testBalance, uint256.NewInt(1))
+		}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, []byte{tx.sender, 19: 0}, tx.isLocal)
+	}
+	reasons, err := pool.AddLocals(context.Background(), txs)
+	require.NoError(err)
+	// local transaction is put back and one of remote ones is evicted instead
+	require.Equal(Success, reasons[0])
+	require.Contains(reasons[1:], QueuedPoolOverflow)
+	require.Equal(2, pool.queued.Len())
+	require.Equal(QueuedSubPool, pool.byHash[string(txs.txs[0].idHash[:])].currentSubPool)
+
+	// local transaction which was put back is removed from queued sub-pool once its nonce is used
+	stateChanges := map[string]senderInfo{string([]byte{1, 19: 0}): {nonce: 2, balance: testBalance}}
+	require.NoError(pool.OnNewBlock(stateChanges, TxSlots{}, TxSlots{}, headerWithBaseFee(1, 10), 1))
+	require.False(pool.IdHashKnown(txs.txs[0].idHash[:]))
+	require.Equal(1, pool.queued.Len())
+}
+
+func toAddr(s string) (addr [20]byte) {
+	copy(addr[:], decodeHex(s))
+	return addr
+}
+
 /*
 func TestSubPoolOrder(t *testing.T) {
 	sub := NewSubPool()
@@ -82,3 +435,386 @@ func hexToSubPool(s string) []uint8 {
 	return a
 }
 */
+
+func TestLocalsToRebroadcast(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+
+	var locals, remotes TxSlots
+	for i, tx := range []struct {
+		nonce, feeCap uint64
+		isLocal       bool
+	}{
+		{nonce: 0, feeCap: 100, isLocal: true},
+		{nonce: 1, feeCap: 5, isLocal: true},   // baseFee sub-pool
+		{nonce: 4, feeCap: 100, isLocal: true}, // nonce gap - queued sub-pool isn't re-announced
+		{nonce: 2, feeCap: 100},
+	} {
+		slot := &TxSlot{nonce: tx.nonce, tip: 1, feeCap: tx.feeCap, gas: 21000}
+		slot.idHash[0] = byte(i + 1)
+		if tx.isLocal {
+			locals.Append(slot, make([]byte, 20), true)
+		} else {
+			remotes.Append(slot, make([]byte, 20), false)
+		}
+	}
+	_, err := pool.AddLocals(context.Background(), locals)
+	require.NoError(err)
+	require.NoError(pool.OnNewTxs(remotes))
+	require.Equal(1, pool.pending.Len())
+	require.Equal(2, pool.baseFee.Len())
+
+	now, interval := time.Now(), time.Minute
+	// transactions were announced when added, first re-announcement is scheduled
+	require.Empty(pool.localsToRebroadcast(now, interval, localRebroadcastLimit))
+	require.Empty(pool.localsToRebroadcast(now.Add(interval-time.Second), interval, localRebroadcastLimit))
+	// limit is respected, the rest is re-announced on next tick
+	require.Equal(toHashes([32]byte{1}), pool.localsToRebroadcast(now.Add(interval), interval, 32))
+	require.Equal(toHashes([32]byte{2}), pool.localsToRebroadcast(now.Add(interval+time.Second), interval, localRebroadcastLimit))
+	// backoff doubles
+	require.Empty(pool.localsToRebroadcast(now.Add(3*interval-time.Second), interval, localRebroadcastLimit))
+	require.Equal(toHashes([32]byte{1}), pool.localsToRebroadcast(now.Add(3*interval), interval, localRebroadcastLimit))
+	require.Equal(toHashes([32]byte{1}, [32]byte{2}), pool.localsToRebroadcast(now.Add(7*interval), interval, localRebroadcastLimit))
+}
+
+func TestSyncToNewPeers(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+	var local, remote TxSlots
+	local.Append(&TxSlot{idHash: [32]byte{1}, tip: 1, feeCap: 100, gas: 21000}, []byte{1, 19: 0}, true)
+	remote.Append(&TxSlot{idHash: [32]byte{2}, tip: 1, feeCap: 100, gas: 21000}, []byte{2, 19: 0}, false)
+	_, err := pool.AddLocals(ctx, local)
+	require.NoError(err)
+	require.NoError(pool.OnNewTxs(remote))
+	require.Eventually(func() bool { return inPool(pool, []byte{2, 31: 0}) }, time.Second, time.Millisecond)
+
+	m := NewMockSentry(ctx)
+	sentryClient := direct.NewSentryClientDirect(direct.ETH66, m)
+	peers := NewPeers()
+	peer := toPeerIDs(1)[0]
+	peers.Add(peer, sentryClient)
+	send := NewSend(ctx, []SentryClient{sentryClient}, pool, peers, log.New())
+	var wg sync.WaitGroup
+	send.SetWaitGroup(&wg)
+	wg.Add(1)
+	timings := DefaultTimings
+	timings.SyncToNewPeersEvery, timings.EvictExpiredEvery = time.Millisecond, 0
+	go BroadcastLoop(ctx, nil, pool, make(chan Hashes), send, nil, timings)
+
+	// new peer receives hashes of all transactions of the pool
+	pool.OnNewPeer(peer)
+	wg.Wait()
+	calls := m.SendMessageByIdCalls()
+	require.Len(calls, 1)
+	require.Equal(peerKey(peer), peerKey(calls[0].SendMessageByIdRequest.PeerId))
+	require.Equal(sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66, calls[0].SendMessageByIdRequest.Data.Id)
+	data := calls[0].SendMessageByIdRequest.Data.Data
+	count, pos, err := ParseHashesCount(data, 0)
+	require.NoError(err)
+	require.Equal(2, count)
+	var announced []Hashes
+	for i := 0; i < count; i++ {
+		var hash []byte
+		hash, pos, err = ParseHash(data, pos, nil)
+		require.NoError(err)
+		announced = append(announced, hash)
+	}
+	require.ElementsMatch([]Hashes{toHashes([32]byte{1}), toHashes([32]byte{2})}, announced)
+}
+
+func TestPrivateTxs(t *testing.T) {
+	require := require.New(t)
+	newPool := func(publish bool) (*TxPool, chan Hashes) {
+		cfg := DefaultConfig
+		cfg.PrivateTxBlocks, cfg.PublishExpiredPrivate = 2, publish
+		pool := newTestPool(t, cfg, nil, headerWithBaseFee(0, 1))
+		return pool, pool.newTxs
+	}
+	var private, public TxSlots
+	private.AppendPrivate(&TxSlot{idHash: [32]byte{1}, tip: 1, feeCap: 10, gas: 21000, rlp: []byte{1}}, []byte{1, 19: 0})
+	public.Append(&TxSlot{idHash: [32]byte{2}, tip: 1, feeCap: 10, gas: 21000, rlp: []byte{2}}, []byte{2, 19: 0}, true)
+	privateHash := private.txs[0].idHash[:]
+
+	pool, newTxs := newPool(false)
+	events, unsubscribe := pool.Subscribe(10)
+	defer unsubscribe()
+	for _, txs := range []TxSlots{private, public} {
+		reasons, err := pool.AddLocals(context.Background(), txs)
+		require.NoError(err)
+		require.Equal([]DiscardReason{Success}, reasons)
+	}
+	require.Equal(PoolStatus{Pending: 2}, pool.Status())
+
+	// private transaction isn't announced, isn't served to peers and isn't re-announced
+	require.Equal(toHashes([32]byte{2}), <-newTxs)
+	require.Nil(pool.GetRlp(privateHash))
+	require.Equal([]byte{1}, pool.GetRlpWithPrivate(privateHash))
+	require.Equal([]byte(toHashes([32]byte{2})), pool.AppendLocalHashes(nil))
+	require.Equal([]byte(toHashes([32]byte{2})), pool.AppendAllHashes(nil))
+	now := time.Now()
+	require.Empty(pool.localsToRebroadcast(now, time.Minute, localRebroadcastLimit))
+	require.Equal(toHashes([32]byte{2}), pool.localsToRebroadcast(now.Add(time.Minute), time.Minute, localRebroadcastLimit))
+	content, ok := pool.SenderContent([20]byte{1})
+	require.True(ok)
+	require.True(content.Txs[0].Private)
+
+	// added for pending block 1, expires at pending block 3
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(1, 1), 1))
+	require.True(pool.IdHashKnown(privateHash))
+	for len(events) > 0 {
+		<-events
+	}
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(2, 1), 1))
+	require.False(pool.IdHashKnown(privateHash))
+	ev := <-events
+	require.Equal(TxDiscarded, ev.Kind)
+	require.Equal(PrivateExpired, ev.Reason)
+	require.Equal(PoolStatus{Pending: 1}, pool.Status())
+
+	// expired transaction becomes ordinary local one
+	pool, newTxs = newPool(true)
+	_, err := pool.AddLocals(context.Background(), private)
+	require.NoError(err)
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(2, 1), 1))
+	require.Equal(toHashes([32]byte{1}), <-newTxs)
+	require.Equal([]byte{1}, pool.GetRlp(privateHash))
+	require.True(pool.IdHashIsLocal(privateHash))
+}
+
+func TestSendersGC(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+	addr1, addr2 := []byte{1, 19: 0}, []byte{2, 19: 0}
+	newTxs := func(nonce uint64, senders ...[]byte) (txs TxSlots) {
+		for _, sender := range senders {
+			slot := &TxSlot{nonce: nonce, tip: 1, feeCap: 100, gas: 21000}
+			slot.idHash[0], slot.idHash[1] = sender[0], byte(nonce)
+			txs.Append(slot, sender, false)
+		}
+		return txs
+	}
+	first := newTxs(0, addr1, addr2)
+	require.NoError(pool.OnNewTxs(first))
+	require.Eventually(func() bool {
+		return inPool(pool, first.txs[0].idHash[:]) && inPool(pool, first.txs[1].idHash[:])
+	}, time.Second, time.Millisecond)
+	id1, _ := pool.senderIDs.id(string(addr1))
+
+	// all transactions of the first sender are mined - it's forgotten
+	mined := newTxs(0, addr1)
+	stateChanges := map[string]senderInfo{string(addr1): {nonce: 1, balance: testBalance}}
+	require.NoError(pool.OnNewBlock(stateChanges, TxSlots{}, mined, headerWithBaseFee(0, 10), 1))
+	require.Equal(1, pool.senderIDs.len())
+	require.Len(pool.senderInfo, 1)
+	_, ok := pool.senderIDs.id(string(addr1))
+	require.False(ok)
+
+	// returning sender gets new id, its state is loaded again
+	returned := newTxs(1, addr1)
+	require.NoError(pool.OnNewTxs(returned))
+	require.Eventually(func() bool { return inPool(pool, returned.txs[0].idHash[:]) }, time.Second, time.Millisecond)
+	id, ok := pool.senderIDs.id(string(addr1))
+	require.True(ok)
+	require.Greater(id, id1)
+	require.Equal(addr1, pool.senderIDs.addr(id))
+
+	// ids are not allocated for unknown senders of mined transactions
+	addr3 := []byte{3, 19: 0}
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, newTxs(0, addr3), headerWithBaseFee(0, 10), 1))
+	_, ok = pool.senderIDs.id(string(addr3))
+	require.False(ok)
+	require.Empty(pool.gcCandidates)
+
+	// sender of mined local transaction is kept while the transaction is in localsHistory
+	local := &TxSlot{nonce: 0, tip: 1, feeCap: 100, gas: 21000}
+	local.idHash[0] = 3
+	var locals TxSlots
+	locals.Append(local, addr3, true)
+	reasons, err := pool.AddLocals(context.Background(), locals)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success}, reasons)
+	stateChanges = map[string]senderInfo{string(addr3): {nonce: 1, balance: testBalance}}
+	require.NoError(pool.OnNewBlock(stateChanges, TxSlots{}, locals, headerWithBaseFee(0, 10), 1))
+	id3, ok := pool.senderIDs.id(string(addr3))
+	require.True(ok)
+	require.Equal(uint64(1), pool.senderInfo[id3].nonce)
+	for i := 0; i < 1024; i++ {
+		pool.localsHistory.Add(i, struct{}{})
+	}
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(0, 10), 1))
+	_, ok = pool.senderIDs.id(string(addr3))
+	require.False(ok)
+}
+
+func TestPoolEvents(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 10))
+	events, unsubscribe := pool.Subscribe(100)
+	addr := [20]byte{1}
+	newTx := func(tip, feeCap uint64) (txs TxSlots) {
+		slot := &TxSlot{nonce: 0, tip: tip, feeCap: feeCap, gas: 21000}
+		slot.idHash[0] = byte(tip)
+		txs.Append(slot, addr[:], true)
+		return txs
+	}
+	next := func() Event {
+		select {
+		case ev := <-events:
+			return ev
+		default:
+			require.FailNow("no event")
+			return Event{}
+		}
+	}
+
+	first := newTx(1, 100)
+	reasons, err := pool.AddLocals(context.Background(), first)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success}, reasons)
+	require.Equal(Event{Kind: TxAdded, Hash: first.txs[0].idHash, Sender: addr, To: QueuedSubPool}, next())
+	require.Equal(Event{Kind: TxPromoted, Hash: first.txs[0].idHash, Sender: addr, From: QueuedSubPool, To: PendingSubPool}, next())
+
+	second := newTx(2, 200)
+	reasons, err = pool.AddLocals(context.Background(), second)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success}, reasons)
+	require.Equal(Event{Kind: TxReplaced, Hash: first.txs[0].idHash, Sender: addr, Reason: Replaced}, next())
+	require.Equal(TxAdded, next().Kind)
+	require.Equal(TxPromoted, next().Kind)
+
+	stateChanges := map[string]senderInfo{string(addr[:]): {nonce: 1, balance: testBalance}}
+	require.NoError(pool.OnNewBlock(stateChanges, TxSlots{}, newTx(2, 200), headerWithBaseFee(0, 10), 1))
+	require.Equal(Event{Kind: TxMined, Hash: second.txs[0].idHash, Sender: addr, Reason: Mined}, next())
+
+	stateChanges = map[string]senderInfo{string(addr[:]): {nonce: 0, balance: testBalance}}
+	require.NoError(pool.OnNewBlock(stateChanges, newTx(2, 200), TxSlots{}, headerWithBaseFee(0, 10), 1))
+	require.Equal(Event{Kind: TxReinjected, Hash: second.txs[0].idHash, Sender: addr, To: PendingSubPool}, next())
+
+	// fee cap below new base fee - demoted
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(0, 300), 1))
+	require.Equal(Event{Kind: TxDemoted, Hash: second.txs[0].idHash, Sender: addr, From: PendingSubPool, To: BaseFeeSubPool}, next())
+
+	// rejected by validation and when added to the sender's transactions, known transaction is not reported
+	invalid, underpriced := newTx(5, 4), newTx(3, 201)
+	reasons, err = pool.AddLocals(context.Background(), invalid)
+	require.NoError(err)
+	require.Equal([]DiscardReason{TipAboveFeeCap}, reasons)
+	require.Equal(Event{Kind: TxRejected, Hash: invalid.txs[0].idHash, Sender: addr, Reason: TipAboveFeeCap}, next())
+	reasons, err = pool.AddLocals(context.Background(), underpriced)
+	require.NoError(err)
+	require.Equal([]DiscardReason{ReplaceUnderpriced}, reasons)
+	require.Equal(Event{Kind: TxRejected, Hash: underpriced.txs[0].idHash, Sender: addr, Reason: ReplaceUnderpriced}, next())
+	reasons, err = pool.AddLocals(context.Background(), newTx(2, 200))
+	require.NoError(err)
+	require.Equal([]DiscardReason{AlreadyKnown}, reasons)
+	require.Empty(events)
+
+	// transactions of the block are unknown - transaction which nonce is used is reported as mined
+	stateChanges = map[string]senderInfo{string(addr[:]): {nonce: 1, balance: testBalance}}
+	require.NoError(pool.OnNewBlock(stateChanges, TxSlots{}, TxSlots{}, headerWithBaseFee(0, 300), 1))
+	require.Equal(Event{Kind: TxMined, Hash: second.txs[0].idHash, Sender: addr, Reason: Mined}, next())
+
+	unsubscribe()
+	_, ok := <-events
+	require.False(ok)
+	unsubscribe()
+}
+
+func TestForkRules(t *testing.T) {
+	require := require.New(t)
+	cfg := DefaultConfig
+	cfg.Chain = ChainConfig{Forks: []uint64{10, 20}, BerlinBlock: 10, LondonBlock: 20}
+	// before Berlin blocks have no base fee and only legacy transactions are accepted
+	pool := newTestPool(t, cfg, nil, headerWithBaseFee(5, 0))
+	newTx := func(txType int, sender byte) (txs TxSlots) {
+		slot := &TxSlot{txType: txType, tip: 1, feeCap: 100, gas: 21000}
+		slot.idHash[0], slot.idHash[1] = byte(txType), sender
+		txs.Append(slot, []byte{sender, 19: 0}, true)
+		return txs
+	}
+	add := func(txs TxSlots) DiscardReason {
+		reasons, err := pool.AddLocals(context.Background(), txs)
+		require.NoError(err)
+		return reasons[0]
+	}
+
+	require.Equal(Success, add(newTx(LegacyTxType, 1)))
+	require.Equal(TxTypeNotSupported, add(newTx(AccessListTxType, 2)))
+	require.Equal(TxTypeNotSupported, add(newTx(DynamicFeeTxType, 3)))
+
+	// block 19 is the last one before London, transactions are validated for the next block
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(19, 0), 1))
+	require.Equal(InitialBaseFee, pool.PendingBaseFee())
+	accessList, dynamicFee := newTx(AccessListTxType, 2), newTx(DynamicFeeTxType, 3)
+	require.Equal(Success, add(accessList))
+	require.Equal(Success, add(dynamicFee))
+
+	// unwind to the block before London - dynamic fee transaction can't be included anymore
+	events, unsubscribe := pool.Subscribe(10)
+	defer unsubscribe()
+	require.NoError(pool.OnNewBlock(nil, TxSlots{}, TxSlots{}, headerWithBaseFee(18, 0), 1))
+	require.False(pool.IdHashKnown(dynamicFee.txs[0].idHash[:]))
+	require.True(pool.IdHashKnown(accessList.txs[0].idHash[:]))
+	require.Equal(PoolStatus{Pending: 2}, pool.Status())
+	ev := <-events
+	require.Equal(TxDiscarded, ev.Kind)
+	require.Equal(TxTypeNotSupported, ev.Reason)
+	require.Equal(dynamicFee.txs[0].idHash, ev.Hash)
+}
+
+func TestEvictExpired(t *testing.T) {
+	require := require.New(t)
+	cfg := DefaultConfig
+	cfg.QueuedLifetime = time.Hour
+	pool := newTestPool(t, cfg, nil, headerWithBaseFee(0, 10))
+	var txs TxSlots
+	for i, tx := range []struct {
+		sender        byte
+		nonce, feeCap uint64
+		isLocal       bool
+	}{
+		{sender: 1, nonce: 5, feeCap: 100},                // nonce gap - queued
+		{sender: 2, nonce: 5, feeCap: 100, isLocal: true}, // local transactions don't expire
+		{sender: 3, nonce: 0, feeCap: 5},                  // baseFee sub-pool
+		{sender: 3, nonce: 1, feeCap: 5},                  // arrives later, queued after nonce gap
+		{sender: 4, nonce: 0, feeCap: 100},                // pending transactions don't expire
+	} {
+		slot := &TxSlot{nonce: tx.nonce, tip: 1, feeCap: tx.feeCap, gas: 21000}
+		slot.idHash[0] = byte(i + 1)
+		txs.Append(slot, []byte{tx.sender, 19: 0}, tx.isLocal)
+	}
+	reasons, err := pool.AddLocals(context.Background(), txs)
+	require.NoError(err)
+	require.Equal([]DiscardReason{Success, Success, Success, Success, Success}, reasons)
+	require.Equal(PoolStatus{Pending: 1, BaseFee: 2, Queued: 2}, pool.Status())
+	now := time.Now()
+	pool.byHash[string(txs.txs[3].idHash[:])].timestamp = now.Add(time.Hour)
+	events, unsubscribe := pool.Subscribe(10)
+	defer unsubscribe()
+
+	pool.evictExpired(now.Add(time.Hour - time.Minute))
+	require.Equal(PoolStatus{Pending: 1, BaseFee: 2, Queued: 2}, pool.Status())
+
+	pool.evictExpired(now.Add(time.Hour + time.Minute))
+	require.Equal(PoolStatus{Pending: 1, Queued: 2}, pool.Status())
+	for _, i := range []int{0, 2} {
+		require.False(pool.IdHashKnown(txs.txs[i].idHash[:]))
+	}
+	_, ok := pool.SenderContent([20]byte{1})
+	require.False(ok)
+	content, ok := pool.SenderContent([20]byte{3})
+	require.True(ok)
+	require.Equal(1, len(content.Txs))
+	require.Equal(QueuedSubPool, content.Txs[0].SubPool)
+	require.Equal(pool.queued.best.Len(), pool.queued.worst.Len())
+	discarded := 0
+	for len(events) > 0 {
+		if ev := <-events; ev.Kind == TxDiscarded {
+			require.Equal(LifetimeExpired, ev.Reason)
+			discarded++
+		}
+	}
+	require.Equal(2, discarded)
+}
@@ -0,0 +1,456 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/rlp"
+)
+
+// maxJsonRpcRequestSize - limit of HTTP request body, enough for batch of transactions of TxPoolConfig.MaxTxSize
+const maxJsonRpcRequestSize = 5 * 1024 * 1024
+
+// JSON-RPC 2.0 error codes, -32000 is used by geth for rejected transactions
+const (
+	jsonRpcParseError     = -32700
+	jsonRpcInvalidRequest = -32600
+	jsonRpcMethodNotFound = -32601
+	jsonRpcInvalidParams  = -32602
+	jsonRpcServerError    = -32000
+)
+
+// JsonRpcServer - HTTP JSON-RPC endpoint of standalone transaction pool, for wallets and monitoring which don't
+// need full RPC daemon. Serves eth_sendRawTransaction, eth_getTransactionByHash, txpool_status, txpool_content and
+// txpool_contentFrom (sub-pools are reported as pending, baseFee and queued). Batches are supported.
+// Start it by http.ListenAndServe
+type JsonRpcServer struct {
+	txPool *TxPool
+
+	parseCtx     *TxParseContext
+	parseCtxLock sync.Mutex // parse context is not thread-safe, but requests are served concurrently
+}
+
+func NewJsonRpcServer(txPool *TxPool) *JsonRpcServer {
+	return &JsonRpcServer{txPool: txPool, parseCtx: NewTxParseContext().WithChainID(txPool.cfg.ChainID)}
+}
+
+type jsonRpcRequest struct {
+	Version string            `json:"jsonrpc"`
+	ID      json.RawMessage   `json:"id"`
+	Method  string            `json:"method"`
+	Params  []json.RawMessage `json:"params"`
+}
+
+type jsonRpcResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"` // "null" if method returned nil
+	Error   *jsonRpcError   `json:"error,omitempty"`
+}
+
+type jsonRpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *jsonRpcError) Error() string { return e.Message }
+
+func (s *JsonRpcServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxJsonRpcRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var reply interface{}
+	if body = bytes.TrimSpace(body); len(body) > 0 && body[0] == '[' {
+		var reqs []jsonRpcRequest
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			reply = errorResponse(nil, &jsonRpcError{Code: jsonRpcParseError, Message: err.Error()})
+		} else {
+			replies := make([]*jsonRpcResponse, 0, len(reqs))
+			for i := range reqs {
+				if resp := s.handle(&reqs[i]); resp != nil {
+					replies = append(replies, resp)
+				}
+			}
+			if len(replies) > 0 {
+				reply = replies
+			}
+		}
+	} else {
+		var req jsonRpcRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			reply = errorResponse(nil, &jsonRpcError{Code: jsonRpcParseError, Message: err.Error()})
+		} else if resp := s.handle(&req); resp != nil {
+			reply = resp
+		}
+	}
+	if reply == nil { // only notifications were received, JSON-RPC 2.0 doesn't reply to them
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(reply)
+}
+
+func errorResponse(id json.RawMessage, err *jsonRpcError) *jsonRpcResponse {
+	if id == nil {
+		id = json.RawMessage("null")
+	}
+	return &jsonRpcResponse{Version: "2.0", ID: id, Error: err}
+}
+
+// handle - response to the request, nil for notification (valid request without id): it's executed, but neither
+// result nor error is reported
+func (s *JsonRpcServer) handle(req *jsonRpcRequest) *jsonRpcResponse {
+	if req.Version != "2.0" || req.Method == "" {
+		return errorResponse(req.ID, &jsonRpcError{Code: jsonRpcInvalidRequest, Message: "invalid request"})
+	}
+	var result interface{}
+	var err *jsonRpcError
+	switch req.Method {
+	case "eth_sendRawTransaction":
+		result, err = s.sendRawTransaction(req.Params)
+	case "eth_getTransactionByHash":
+		result, err = s.getTransactionByHash(req.Params)
+	case "txpool_status":
+		status := s.txPool.Status()
+		result = map[string]string{"pending": hexUint64(uint64(status.Pending)), "baseFee": hexUint64(uint64(status.BaseFee)), "queued": hexUint64(uint64(status.Queued))}
+	case "txpool_content":
+		var contentErr error
+		if result, contentErr = contentBySubPool(s.txPool.Content()); contentErr != nil {
+			err = &jsonRpcError{Code: jsonRpcServerError, Message: contentErr.Error()}
+		}
+	case "txpool_contentFrom":
+		result, err = s.contentFrom(req.Params)
+	default:
+		err = &jsonRpcError{Code: jsonRpcMethodNotFound, Message: fmt.Sprintf("the method %s does not exist/is not available", req.Method)}
+	}
+	if req.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return errorResponse(req.ID, err)
+	}
+	encoded, encodeErr := json.Marshal(result)
+	if encodeErr != nil {
+		return errorResponse(req.ID, &jsonRpcError{Code: jsonRpcServerError, Message: encodeErr.Error()})
+	}
+	return &jsonRpcResponse{Version: "2.0", ID: req.ID, Result: encoded}
+}
+
+// sendRawTransaction - adds transaction as local one, returns its hash. Rejected transaction is reported as error
+// with DiscardReason, same way as geth does
+func (s *JsonRpcServer) sendRawTransaction(params []json.RawMessage) (interface{}, *jsonRpcError) {
+	rlp, err := bytesParam(params, 0)
+	if err != nil {
+		return nil, err
+	}
+	rlp = wrapTypedTx(rlp)
+	s.parseCtxLock.Lock()
+	slot, sender, parseErr := s.parseCtx.ParseSingleTransaction(rlp)
+	s.parseCtxLock.Unlock()
+	if parseErr != nil {
+		return nil, &jsonRpcError{Code: jsonRpcServerError, Message: parseErr.Error()}
+	}
+	var slots TxSlots
+	slots.Append(slot, sender[:], true)
+	reasons, addErr := s.txPool.AddLocals(context.Background(), slots)
+	if addErr != nil {
+		return nil, &jsonRpcError{Code: jsonRpcServerError, Message: addErr.Error()}
+	}
+	if reasons[0] != Success {
+		return nil, &jsonRpcError{Code: jsonRpcServerError, Message: reasons[0].String()}
+	}
+	return hexBytes(slot.idHash[:]), nil
+}
+
+// getTransactionByHash - transaction of the pool, null if it's unknown. Pool keeps only RLP of transactions, so
+// it's parsed again to fill the fields
+func (s *JsonRpcServer) getTransactionByHash(params []json.RawMessage) (interface{}, *jsonRpcError) {
+	hash, err := bytesParam(params, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(hash) != 32 {
+		return nil, &jsonRpcError{Code: jsonRpcInvalidParams, Message: "hash must be 32 bytes"}
+	}
+	rlp := s.txPool.GetRlpWithPrivate(hash)
+	if rlp == nil {
+		return nil, nil
+	}
+	s.parseCtxLock.Lock()
+	slot, sender, parseErr := s.parseCtx.ParseSingleTransaction(rlp)
+	s.parseCtxLock.Unlock()
+	if parseErr != nil {
+		return nil, &jsonRpcError{Code: jsonRpcServerError, Message: parseErr.Error()}
+	}
+	res, rpcErr := newRpcTx(sender, newTxContent(slot))
+	if rpcErr != nil {
+		return nil, &jsonRpcError{Code: jsonRpcServerError, Message: rpcErr.Error()}
+	}
+	return res, nil
+}
+
+func (s *JsonRpcServer) contentFrom(params []json.RawMessage) (interface{}, *jsonRpcError) {
+	addrBytes, err := bytesParam(params, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(addrBytes) != 20 {
+		return nil, &jsonRpcError{Code: jsonRpcInvalidParams, Message: "address must be 20 bytes"}
+	}
+	var addr [20]byte
+	copy(addr[:], addrBytes)
+	bySubPool := map[string]map[string]*rpcTx{}
+	for _, t := range []SubPoolType{PendingSubPool, BaseFeeSubPool, QueuedSubPool} {
+		bySubPool[t.String()] = map[string]*rpcTx{}
+	}
+	if content, ok := s.txPool.SenderContent(addr); ok {
+		for _, tx := range content.Txs {
+			res, rpcErr := newRpcTx(addr, tx)
+			if rpcErr != nil {
+				return nil, &jsonRpcError{Code: jsonRpcServerError, Message: rpcErr.Error()}
+			}
+			bySubPool[tx.SubPool.String()][strconv.FormatUint(tx.Nonce, 10)] = res
+		}
+	}
+	return bySubPool, nil
+}
+
+// rpcTx - transaction in the format of eth_getTransactionByHash, for pooled transaction block fields are null.
+// GasPrice of dynamic fee transaction is its fee cap, same as geth reports for pending transactions
+type rpcTx struct {
+	BlockHash            *string           `json:"blockHash"`
+	BlockNumber          *string           `json:"blockNumber"`
+	TransactionIndex     *string           `json:"transactionIndex"`
+	Type                 string            `json:"type"`
+	Hash                 string            `json:"hash"`
+	From                 string            `json:"from"`
+	To                   *string           `json:"to"` // null for contract creation
+	Nonce                string            `json:"nonce"`
+	Gas                  string            `json:"gas"`
+	GasPrice             string            `json:"gasPrice"`
+	MaxPriorityFeePerGas string            `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerGas         string            `json:"maxFeePerGas,omitempty"`
+	Value                string            `json:"value"`
+	Input                string            `json:"input"`
+	ChainID              string            `json:"chainId,omitempty"`
+	AccessList           *[]rpcAccessTuple `json:"accessList,omitempty"`
+	V                    string            `json:"v"`
+	R                    string            `json:"r"`
+	S                    string            `json:"s"`
+	Raw                  string            `json:"raw"`
+}
+
+type rpcAccessTuple struct {
+	Address     string   `json:"address"`
+	StorageKeys []string `json:"storageKeys"`
+}
+
+func newRpcTx(sender [20]byte, tx TxContent) (*rpcTx, error) {
+	raw := unwrapTypedTx(tx.Rlp)
+	res := &rpcTx{
+		Hash:  hexBytes(tx.Hash[:]),
+		From:  hexBytes(sender[:]),
+		Nonce: hexUint64(tx.Nonce),
+		Gas:   hexUint64(tx.Gas),
+		Value: tx.Value.Hex(),
+		Raw:   hexBytes(raw),
+	}
+	if err := res.decodeRlp(raw); err != nil {
+		return nil, fmt.Errorf("transaction %x: %w", tx.Hash, err)
+	}
+	return res, nil
+}
+
+// decodeRlp - fills fields which TxContent doesn't keep from canonical encoding of the transaction. Fields come
+// in the order of legacy: [nonce, gasPrice, gas, to, value, data, v, r, s], access list (type 1):
+// [chainId, nonce, gasPrice, gas, to, value, data, accessList, yParity, r, s] and dynamic fee (type 2):
+// [chainId, nonce, tip, feeCap, gas, to, value, data, accessList, yParity, r, s]
+func (t *rpcTx) decodeRlp(payload []byte) (err error) {
+	if len(payload) == 0 {
+		return fmt.Errorf("empty rlp")
+	}
+	txType, p := LegacyTxType, 0
+	if payload[0] < 0xc0 {
+		txType, p = int(payload[0]), 1
+	}
+	t.Type = hexUint64(uint64(txType))
+	if p, _, err = rlp.List(payload, p); err != nil {
+		return err
+	}
+	var n uint256.Int
+	if txType != LegacyTxType {
+		if p, err = rlp.U256(payload, p, &n); err != nil {
+			return fmt.Errorf("chainId: %w", err)
+		}
+		t.ChainID = n.Hex()
+	}
+	if p, _, err = rlp.U64(payload, p); err != nil { // nonce is already known
+		return fmt.Errorf("nonce: %w", err)
+	}
+	if txType == DynamicFeeTxType {
+		if p, err = rlp.U256(payload, p, &n); err != nil {
+			return fmt.Errorf("tip: %w", err)
+		}
+		t.MaxPriorityFeePerGas = n.Hex()
+		if p, err = rlp.U256(payload, p, &n); err != nil {
+			return fmt.Errorf("feeCap: %w", err)
+		}
+		t.MaxFeePerGas = n.Hex()
+	} else if p, err = rlp.U256(payload, p, &n); err != nil {
+		return fmt.Errorf("gasPrice: %w", err)
+	}
+	t.GasPrice = n.Hex()
+	if p, _, err = rlp.U64(payload, p); err != nil { // gas is already known
+		return fmt.Errorf("gas: %w", err)
+	}
+	dataPos, dataLen, err := rlp.String(payload, p)
+	if err != nil {
+		return fmt.Errorf("to: %w", err)
+	}
+	if dataLen > 0 {
+		to := hexBytes(payload[dataPos : dataPos+dataLen])
+		t.To = &to
+	}
+	if dataPos, dataLen, err = rlp.String(payload, dataPos+dataLen); err != nil { // value is already known
+		return fmt.Errorf("value: %w", err)
+	}
+	if dataPos, dataLen, err = rlp.String(payload, dataPos+dataLen); err != nil {
+		return fmt.Errorf("data: %w", err)
+	}
+	t.Input = hexBytes(payload[dataPos : dataPos+dataLen])
+	p = dataPos + dataLen
+	if txType != LegacyTxType {
+		accessList := []rpcAccessTuple{}
+		if p, err = decodeAccessList(payload, p, &accessList); err != nil {
+			return fmt.Errorf("accessList: %w", err)
+		}
+		t.AccessList = &accessList
+	}
+	if p, err = rlp.U256(payload, p, &n); err != nil {
+		return fmt.Errorf("v: %w", err)
+	}
+	t.V = n.Hex()
+	if txType == LegacyTxType && n.GtUint64(34) { // EIP-155: v = chainId * 2 + 35 + yParity
+		t.ChainID = n.Rsh(n.SubUint64(&n, 35), 1).Hex()
+	}
+	if p, err = rlp.U256(payload, p, &n); err != nil {
+		return fmt.Errorf("r: %w", err)
+	}
+	t.R = n.Hex()
+	if _, err = rlp.U256(payload, p, &n); err != nil {
+		return fmt.Errorf("s: %w", err)
+	}
+	t.S = n.Hex()
+	return nil
+}
+
+// decodeAccessList - list of [address, [storageKey, ...]], returns position after the list
+func decodeAccessList(payload []byte, pos int, accessList *[]rpcAccessTuple) (int, error) {
+	dataPos, dataLen, err := rlp.List(payload, pos)
+	if err != nil {
+		return 0, err
+	}
+	for p := dataPos; p < dataPos+dataLen; {
+		tuplePos, tupleLen, err := rlp.List(payload, p)
+		if err != nil {
+			return 0, err
+		}
+		addrPos, err := rlp.StringOfLen(payload, tuplePos, 20)
+		if err != nil {
+			return 0, fmt.Errorf("address: %w", err)
+		}
+		tuple := rpcAccessTuple{Address: hexBytes(payload[addrPos : addrPos+20]), StorageKeys: []string{}}
+		keysPos, keysLen, err := rlp.List(payload, addrPos+20)
+		if err != nil {
+			return 0, fmt.Errorf("storage keys: %w", err)
+		}
+		for k := keysPos; k < keysPos+keysLen; {
+			keyPos, err := rlp.StringOfLen(payload, k, 32)
+			if err != nil {
+				return 0, fmt.Errorf("storage key: %w", err)
+			}
+			tuple.StorageKeys = append(tuple.StorageKeys, hexBytes(payload[keyPos:keyPos+32]))
+			k = keyPos + 32
+		}
+		*accessList = append(*accessList, tuple)
+		p = tuplePos + tupleLen
+	}
+	return dataPos + dataLen, nil
+}
+
+// contentBySubPool - format of txpool_content: sub-pool => sender => nonce => transaction
+func contentBySubPool(content []*SenderContent) (map[string]map[string]map[string]*rpcTx, error) {
+	bySubPool := map[string]map[string]map[string]*rpcTx{}
+	for _, t := range []SubPoolType{PendingSubPool, BaseFeeSubPool, QueuedSubPool} {
+		bySubPool[t.String()] = map[string]map[string]*rpcTx{}
+	}
+	for _, sender := range content {
+		addr := hexBytes(sender.Address[:])
+		for _, tx := range sender.Txs {
+			sub := bySubPool[tx.SubPool.String()]
+			if sub[addr] == nil {
+				sub[addr] = map[string]*rpcTx{}
+			}
+			res, err := newRpcTx(sender.Address, tx)
+			if err != nil {
+				return nil, err
+			}
+			sub[addr][strconv.FormatUint(tx.Nonce, 10)] = res
+		}
+	}
+	return bySubPool, nil
+}
+
+func bytesParam(params []json.RawMessage, i int) ([]byte, *jsonRpcError) {
+	if len(params) <= i {
+		return nil, &jsonRpcError{Code: jsonRpcInvalidParams, Message: fmt.Sprintf("missing value for required argument %d", i)}
+	}
+	var str string
+	if err := json.Unmarshal(params[i], &str); err != nil {
+		return nil, &jsonRpcError{Code: jsonRpcInvalidParams, Message: fmt.Sprintf("argument %d: %s", i, err)}
+	}
+	if !strings.HasPrefix(str, "0x") && !strings.HasPrefix(str, "0X") {
+		return nil, &jsonRpcError{Code: jsonRpcInvalidParams, Message: fmt.Sprintf("argument %d: hex string without 0x prefix", i)}
+	}
+	b, err := hex.DecodeString(str[2:])
+	if err != nil {
+		return nil, &jsonRpcError{Code: jsonRpcInvalidParams, Message: fmt.Sprintf("argument %d: %s", i, err)}
+	}
+	return b, nil
+}
+
+func hexBytes(b []byte) string { return "0x" + hex.EncodeToString(b) }
+
+func hexUint64(n uint64) string { return "0x" + strconv.FormatUint(n, 16) }
@@ -0,0 +1,190 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJsonRpcServer(t *testing.T) {
+	require := require.New(t)
+	pool := newTestPool(t, DefaultConfig, nil, headerWithBaseFee(0, 1))
+	srv := httptest.NewServer(NewJsonRpcServer(pool))
+	defer srv.Close()
+
+	call := func(body string) []byte {
+		resp, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+		require.NoError(err)
+		defer resp.Body.Close()
+		require.Equal(http.StatusOK, resp.StatusCode)
+		var raw json.RawMessage
+		require.NoError(json.NewDecoder(resp.Body).Decode(&raw))
+		return raw
+	}
+	type response struct {
+		ID     int             `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *jsonRpcError   `json:"error"`
+	}
+	request := func(method string, params ...string) response {
+		var resp response
+		body := `{"jsonrpc":"2.0","id":1,"method":"` + method + `","params":["` + strings.Join(params, `","`) + `"]}`
+		require.NoError(json.Unmarshal(call(body), &resp))
+		return resp
+	}
+	tx := txParseTests[0]
+	hash, sender := "0x"+tx.idHashStr, "0x"+tx.senderStr
+
+	resp := request("eth_sendRawTransaction", "0x"+tx.payloadStr)
+	require.Nil(resp.Error)
+	require.JSONEq(`"`+hash+`"`, string(resp.Result))
+	resp = request("eth_sendRawTransaction", "0x"+tx.payloadStr)
+	require.Equal(jsonRpcServerError, resp.Error.Code)
+	require.Equal(AlreadyKnown.String(), resp.Error.Message)
+	resp = request("eth_sendRawTransaction", "0xc3010203")
+	require.Equal(jsonRpcServerError, resp.Error.Code)
+	resp = request("eth_sendRawTransaction", "c3010203")
+	require.Equal(jsonRpcInvalidParams, resp.Error.Code)
+
+	var got rpcTx
+	resp = request("eth_getTransactionByHash", hash)
+	require.Nil(resp.Error)
+	require.NoError(json.Unmarshal(resp.Result, &got))
+	require.Equal(hash, got.Hash)
+	require.Equal(sender, got.From)
+	require.Equal("0x"+tx.payloadStr, got.Raw)
+	require.Nil(got.BlockHash)
+	require.Equal("0x0", got.Type)
+	require.Equal("0xfe3b557e8fb62b89f4916b721be55ceb828dbd73", *got.To)
+	require.Equal("0x", got.Input)
+	require.Equal("0x59682f00", got.GasPrice)
+	require.Empty(got.MaxFeePerGas)
+	require.Empty(got.ChainID) // unprotected
+	require.Nil(got.AccessList)
+	require.Equal("0x1c", got.V)
+	require.Equal("0xd22fc3eed9b9b9dbef9eec230aa3fb849eff60356c6b34e86155dca5c03554c7", got.R)
+	require.Equal("0x5e3903d7375337f103cb9583d97a59dcca7472908c31614ae240c6a8311b02d6", got.S)
+	// content is keyed by decimal nonce, same as in geth
+	nonce, err := strconv.ParseUint(strings.TrimPrefix(got.Nonce, "0x"), 16, 64)
+	require.NoError(err)
+	nonceKey := strconv.FormatUint(nonce, 10)
+	resp = request("eth_getTransactionByHash", "0x"+strings.Repeat("00", 32))
+	require.Nil(resp.Error)
+	require.Equal("null", string(resp.Result))
+
+	resp = request("txpool_status")
+	require.JSONEq(`{"pending":"0x1","baseFee":"0x0","queued":"0x0"}`, string(resp.Result))
+
+	var content map[string]map[string]map[string]rpcTx
+	resp = request("txpool_content")
+	require.NoError(json.Unmarshal(resp.Result, &content))
+	require.Len(content["pending"][sender], 1)
+	require.Equal(hash, content["pending"][sender][nonceKey].Hash)
+	require.Empty(content["queued"])
+
+	var contentFrom map[string]map[string]rpcTx
+	resp = request("txpool_contentFrom", sender)
+	require.NoError(json.Unmarshal(resp.Result, &contentFrom))
+	require.Equal(hash, contentFrom["pending"][nonceKey].Hash)
+
+	// typed transaction is sent and returned in canonical encoding, without RLP string prefix of p2p messages
+	typed := txParseTests[2]
+	canonical := "0x" + typed.payloadStr[4:]
+	resp = request("eth_sendRawTransaction", canonical)
+	require.Nil(resp.Error)
+	require.JSONEq(`"0x`+typed.idHashStr+`"`, string(resp.Result))
+	resp = request("eth_getTransactionByHash", "0x"+typed.idHashStr)
+	require.Nil(resp.Error)
+	got = rpcTx{}
+	require.NoError(json.Unmarshal(resp.Result, &got))
+	require.Equal(canonical, got.Raw)
+	require.Equal("0x2", got.Type)
+	require.Equal("0x7b", got.ChainID)
+	require.Equal("0x3b9aca00", got.MaxPriorityFeePerGas)
+	require.Equal("0x3b9aca00", got.MaxFeePerGas)
+	require.Equal("0x3b9aca00", got.GasPrice)
+	require.Equal("0xe80d2a018c813577f33f9e69387dc621206fb3a4", *got.To)
+	require.Equal([]rpcAccessTuple{}, *got.AccessList)
+	require.Equal("0x1", got.V)
+
+	// batch: ids are kept, unknown method is reported per request
+	var batch []response
+	require.NoError(json.Unmarshal(call(`[{"jsonrpc":"2.0","id":1,"method":"txpool_status"},{"jsonrpc":"2.0","id":2,"method":"eth_mining"}]`), &batch))
+	require.Len(batch, 2)
+	require.Nil(batch[0].Error)
+	require.Equal(2, batch[1].ID)
+	require.Equal(jsonRpcMethodNotFound, batch[1].Error.Code)
+
+	// notifications are executed, but not replied
+	require.NoError(json.Unmarshal(call(`[{"jsonrpc":"2.0","method":"txpool_status"},{"jsonrpc":"2.0","id":3,"method":"txpool_status"}]`), &batch))
+	require.Len(batch, 1)
+	require.Equal(3, batch[0].ID)
+	for _, body := range []string{`{"jsonrpc":"2.0","method":"eth_mining"}`, `[{"jsonrpc":"2.0","method":"txpool_status"}]`} {
+		notification, err := http.Post(srv.URL, "application/json", strings.NewReader(body))
+		require.NoError(err)
+		empty, err := io.ReadAll(notification.Body)
+		require.NoError(err)
+		notification.Body.Close()
+		require.Equal(http.StatusNoContent, notification.StatusCode)
+		require.Empty(empty)
+	}
+
+	resp = response{}
+	require.NoError(json.Unmarshal(call(`{"jsonrpc":"2.0"`), &resp))
+	require.Equal(jsonRpcParseError, resp.Error.Code)
+
+	get, err := http.Get(srv.URL)
+	require.NoError(err)
+	get.Body.Close()
+	require.Equal(http.StatusMethodNotAllowed, get.StatusCode)
+}
+
+func TestRpcTxDecodeRlp(t *testing.T) {
+	require := require.New(t)
+	// protected legacy transaction: chainId is recovered from v
+	var legacy rpcTx
+	require.NoError(legacy.decodeRlp(decodeHex(txParseTests[1].payloadStr)))
+	require.Equal("0x7b", legacy.ChainID)
+	require.Equal("0x11a", legacy.V)
+
+	// access list transaction which creates contract: [chainId, nonce, gasPrice, gas, to, value, data, accessList, yParity, r, s]
+	addr, key := strings.Repeat("aa", 20), strings.Repeat("bb", 32)
+	accessList := "f838" + "f7" + "94" + addr + "e1" + "a0" + key
+	payload := "01" + "f844" + "01" + "80" + "01" + "01" + "80" + "80" + "01" + accessList + "80" + "01" + "02"
+	var typed rpcTx
+	require.NoError(typed.decodeRlp(decodeHex(payload)))
+	require.Equal("0x1", typed.Type)
+	require.Equal("0x1", typed.ChainID)
+	require.Equal("0x1", typed.GasPrice)
+	require.Empty(typed.MaxFeePerGas)
+	require.Nil(typed.To)
+	require.Equal("0x01", typed.Input)
+	require.Equal([]rpcAccessTuple{{Address: "0x" + addr, StorageKeys: []string{"0x" + key}}}, *typed.AccessList)
+	require.Equal("0x0", typed.V)
+	require.Equal("0x1", typed.R)
+	require.Equal("0x2", typed.S)
+
+	require.Error(typed.decodeRlp(decodeHex(payload[:len(payload)-2])))
+}
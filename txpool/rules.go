@@ -0,0 +1,90 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"fmt"
+	"math"
+)
+
+// NotActivated - activation block of a fork which is not scheduled
+const NotActivated uint64 = math.MaxUint64
+
+// ChainConfig - fork schedule of the chain, in one place: NewFetch announces Forks in eth handshake and New derives
+// ChainRules of the pool from it (see TxPoolConfig.Chain), so both follow the same forks. Zero value activates
+// all forks at genesis
+type ChainConfig struct {
+	// Forks - blocks of all forks, as in eth handshake: forks activated at genesis are not in the list
+	Forks []uint64
+	// BerlinBlock, LondonBlock - activation blocks of forks which change transaction acceptance rules: zero - at
+	// genesis, NotActivated - not scheduled. Other blocks must be in Forks
+	BerlinBlock, LondonBlock uint64
+}
+
+// Rules - ChainRules of the chain, error if activation blocks don't match Forks
+func (c ChainConfig) Rules() (ChainRules, error) {
+	known := func(block uint64) bool {
+		if block == 0 || block == NotActivated {
+			return true
+		}
+		for _, fork := range c.Forks {
+			if fork == block {
+				return true
+			}
+		}
+		return false
+	}
+	if !known(c.BerlinBlock) {
+		return ChainRules{}, fmt.Errorf("berlin block %d is not in the list of forks %v", c.BerlinBlock, c.Forks)
+	}
+	if !known(c.LondonBlock) {
+		return ChainRules{}, fmt.Errorf("london block %d is not in the list of forks %v", c.LondonBlock, c.Forks)
+	}
+	if c.LondonBlock < c.BerlinBlock {
+		return ChainRules{}, fmt.Errorf("london block %d is before berlin block %d", c.LondonBlock, c.BerlinBlock)
+	}
+	return ChainRules{BerlinBlock: c.BerlinBlock, LondonBlock: c.LondonBlock}, nil
+}
+
+// ChainRules - blocks at which forks changing transaction acceptance rules are activated, see ChainConfig.Rules.
+// Zero value activates all of them at genesis
+type ChainRules struct {
+	BerlinBlock uint64 // EIP-2930: AccessListTxType is accepted
+	LondonBlock uint64 // EIP-1559: DynamicFeeTxType is accepted, blocks have base fee
+}
+
+func (r ChainRules) IsBerlin(height uint64) bool { return height >= r.BerlinBlock }
+func (r ChainRules) IsLondon(height uint64) bool { return height >= r.LondonBlock }
+
+// TxTypeSupported - can transaction of txType be included into block of given height
+func (r ChainRules) TxTypeSupported(txType int, height uint64) bool {
+	switch txType {
+	case LegacyTxType:
+		return true
+	case AccessListTxType:
+		return r.IsBerlin(height)
+	case DynamicFeeTxType:
+		return r.IsLondon(height)
+	default:
+		return false
+	}
+}
+
+// forksChanged - rules of block pendingHeight differ from rules of block prevPendingHeight
+func (r ChainRules) forksChanged(prevPendingHeight, pendingHeight uint64) bool {
+	return r.IsBerlin(prevPendingHeight) != r.IsBerlin(pendingHeight) || r.IsLondon(prevPendingHeight) != r.IsLondon(pendingHeight)
+}
@@ -0,0 +1,50 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestChainRules(t *testing.T) {
+	require := require.New(t)
+	forks := []uint64{1150000, 12244000, 12965000}
+
+	rules, err := ChainConfig{Forks: forks, BerlinBlock: 12244000, LondonBlock: 12965000}.Rules()
+	require.NoError(err)
+	require.True(rules.TxTypeSupported(LegacyTxType, 0))
+	require.False(rules.TxTypeSupported(AccessListTxType, 12243999))
+	require.True(rules.TxTypeSupported(AccessListTxType, 12244000))
+	require.False(rules.TxTypeSupported(DynamicFeeTxType, 12964999))
+	require.True(rules.TxTypeSupported(DynamicFeeTxType, 12965000))
+	require.False(rules.TxTypeSupported(DynamicFeeTxType+1, 12965000))
+	require.True(rules.forksChanged(12964999, 12965000))
+	require.False(rules.forksChanged(12965000, 12965001))
+
+	// forks at genesis are not in the list, not scheduled forks - too
+	rules, err = ChainConfig{Forks: forks, LondonBlock: NotActivated}.Rules()
+	require.NoError(err)
+	require.True(rules.IsBerlin(0))
+	require.False(rules.IsLondon(12965000))
+
+	_, err = ChainConfig{Forks: forks, BerlinBlock: 12244001, LondonBlock: 12965000}.Rules()
+	require.Error(err)
+	_, err = ChainConfig{Forks: forks, BerlinBlock: 12965000, LondonBlock: 12244000}.Rules()
+	require.Error(err)
+}
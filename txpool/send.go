@@ -18,6 +18,8 @@ package txpool
 
 import (
 	"context"
+	"math"
+	"math/rand"
 	"sync"
 
 	"github.com/ledgerwatch/erigon-lib/direct"
@@ -37,16 +39,18 @@ type Send struct {
 	ctx           context.Context
 	sentryClients []SentryClient // sentry clients that will be used for accessing the network
 	pool          Pool
+	peers         *Peers // connected peers and hashes they know, to not announce same hashes to them again
 
 	logger log.Logger
 	wg     *sync.WaitGroup
 }
 
-func NewSend(ctx context.Context, sentryClients []SentryClient, pool Pool, logger log.Logger) *Send {
+func NewSend(ctx context.Context, sentryClients []SentryClient, pool Pool, peers *Peers, logger log.Logger) *Send {
 	return &Send{
 		ctx:           ctx,
 		pool:          pool,
 		sentryClients: sentryClients,
+		peers:         peers,
 		logger:        logger.New("at", "TxPool.Send"),
 	}
 }
@@ -67,128 +71,146 @@ func (f *Send) notifyTests() {
 	}
 }
 
+// BroadcastLocalPooledTxs - propagates transactions to all connected peers, which don't know them yet (see
+// propagate). Returns amount of peers transactions were sent to
 func (f *Send) BroadcastLocalPooledTxs(txs Hashes) (sentToPeers int) {
 	defer f.notifyTests()
-	if len(txs) == 0 {
-		return
-	}
+	return f.propagate(txs)
+}
 
-	avgPeersPerSent65 := 0
-	avgPeersPerSent66 := 0
-	for len(txs) > 0 {
-		var pending Hashes
-		if len(txs) > p2pTxPacketLimit {
-			pending = txs[:p2pTxPacketLimit]
-			txs = txs[p2pTxPacketLimit:]
-		} else {
-			pending = txs[:]
-			txs = txs[:0]
-		}
+func (f *Send) BroadcastRemotePooledTxs(txs Hashes) {
+	defer f.notifyTests()
+	f.propagate(txs)
+}
 
-		data := EncodeHashes(pending, nil)
-		var req66, req65 *sentry.OutboundMessageData
-		for _, sentryClient := range f.sentryClients {
-			//if !sentryClient.Ready() {
-			//	continue
-			//}
-			switch sentryClient.Protocol() {
-			case direct.ETH65:
-				if req65 == nil {
-					req65 = &sentry.OutboundMessageData{
-						Id:   sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_65,
-						Data: data,
-					}
-				}
-
-				peers, err := sentryClient.SendMessageToAll(f.ctx, req65, &grpc.EmptyCallOption{})
-				if err != nil {
-					f.logger.Warn("sentry response", "err", err)
-				}
-				avgPeersPerSent65 += len(peers.Peers)
-
-			case direct.ETH66:
-				if req66 == nil {
-					req66 = &sentry.OutboundMessageData{
-						Id:   sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66,
-						Data: data,
-					}
-				}
-				peers, err := sentryClient.SendMessageToAll(f.ctx, req66, &grpc.EmptyCallOption{})
-				if err != nil {
-					f.logger.Warn("sentry response", "err", err)
-				}
-				avgPeersPerSent66 += len(peers.Peers)
-			}
-		}
+// RebroadcastLocalPooledTxs - re-announces hashes of local transactions to all connected peers, also to ones
+// which know them: peer could drop transaction from its pool since. Returns amount of peers hashes were sent to
+func (f *Send) RebroadcastLocalPooledTxs(txs Hashes) (sentToPeers int) {
+	defer f.notifyTests()
+	if len(txs) == 0 {
+		return 0
+	}
+	for _, peer := range f.peers.all() {
+		f.sendHashes(peer.sentry, peer.id, txs)
+		peer.markKnown(txs)
+		sentToPeers++
 	}
-	return avgPeersPerSent65 + avgPeersPerSent66
+	return sentToPeers
 }
 
-func (f *Send) BroadcastRemotePooledTxs(txs Hashes) {
+func (f *Send) PropagatePooledTxsToPeersList(peers []PeerID, txs []byte) {
 	defer f.notifyTests()
 
 	if len(txs) == 0 {
 		return
 	}
-
-	for len(txs) > 0 {
-		var pending Hashes
-		if len(txs) > p2pTxPacketLimit {
-			pending = txs[:p2pTxPacketLimit]
-			txs = txs[p2pTxPacketLimit:]
-		} else {
-			pending = txs[:]
-			txs = txs[:0]
+	for _, id := range peers {
+		if peer := f.peers.get(id); peer != nil {
+			f.announce([]*peerInfo{peer}, txs)
+			continue
 		}
-
-		data := EncodeHashes(pending, nil)
-		var req66, req65 *sentry.SendMessageToRandomPeersRequest
+		// sentry of the peer is unknown
 		for _, sentryClient := range f.sentryClients {
-			//if !sentryClient.Ready() {
-			//	continue
-			//}
-
-			switch sentryClient.Protocol() {
-			case direct.ETH65:
-				if req65 == nil {
-					req65 = &sentry.SendMessageToRandomPeersRequest{
-						MaxPeers: 1024,
-						Data: &sentry.OutboundMessageData{
-							Id:   sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_65,
-							Data: data,
-						},
-					}
-				}
-
-				if _, err := sentryClient.SendMessageToRandomPeers(f.ctx, req65, &grpc.EmptyCallOption{}); err != nil {
-					f.logger.Warn("sentry response", "err", err)
-				}
-
-			case direct.ETH66:
-				if req66 == nil {
-					req66 = &sentry.SendMessageToRandomPeersRequest{
-						MaxPeers: 1024,
-						Data: &sentry.OutboundMessageData{
-							Id:   sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66,
-							Data: data,
-						},
-					}
-				}
-				if _, err := sentryClient.SendMessageToRandomPeers(f.ctx, req66, &grpc.EmptyCallOption{}); err != nil {
-					f.logger.Warn("sentry response", "err", err)
-				}
-			}
+			f.sendHashes(sentryClient, id, txs)
 		}
 	}
 }
 
-func (f *Send) PropagatePooledTxsToPeersList(peers []PeerID, txs []byte) {
-	defer f.notifyTests()
+// propagate - same as in eth/65: full transactions are sent to sqrt(peers) random peers, the rest of peers
+// receive only hashes and request transactions they need. Transactions are not sent to peers which know them
+func (f *Send) propagate(txs Hashes) (sentToPeers int) {
+	if len(txs) == 0 {
+		return 0
+	}
+	peers := f.peers.all()
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	fullTo := int(math.Sqrt(float64(len(peers))))
+	sentToPeers = f.sendTxsTo(peers[:fullTo], txs)
+	return sentToPeers + f.announce(peers[fullTo:], txs)
+}
+
+// sendTxsTo - sends to every peer full transactions which it doesn't know, and remembers that it knows them now
+func (f *Send) sendTxsTo(peers []*peerInfo, txs Hashes) (sentToPeers int) {
+	for _, peer := range peers {
+		unknown := peer.unknown(txs)
+		if len(unknown) == 0 {
+			continue
+		}
+		f.sendTxs(peer.sentry, peer.id, unknown)
+		peer.markKnown(unknown)
+		sentToPeers++
+	}
+	return sentToPeers
+}
 
+// announce - sends to every peer hashes which it doesn't know, and remembers that it knows them now
+func (f *Send) announce(peers []*peerInfo, txs Hashes) (sentToPeers int) {
 	if len(txs) == 0 {
+		return 0
+	}
+	for _, peer := range peers {
+		unknown := peer.unknown(txs)
+		if len(unknown) == 0 {
+			continue
+		}
+		f.sendHashes(peer.sentry, peer.id, unknown)
+		peer.markKnown(unknown)
+		sentToPeers++
+	}
+	return sentToPeers
+}
+
+// sendTxs - sends TRANSACTIONS with RLP of transactions from the pool, split to messages of p2pTxPacketLimit.
+// Transactions which already left the pool are skipped
+func (f *Send) sendTxs(sentryClient SentryClient, peer PeerID, txs Hashes) {
+	var messageId sentry.MessageId
+	switch sentryClient.Protocol() {
+	case direct.ETH65:
+		messageId = sentry.MessageId_TRANSACTIONS_65
+	case direct.ETH66:
+		messageId = sentry.MessageId_TRANSACTIONS_66
+	default:
 		return
 	}
+	var pending [][]byte
+	pendingSize := 0
+	for i := 0; i <= txs.Len(); i++ {
+		if i < txs.Len() {
+			rlp := f.pool.GetRlp(txs.At(i))
+			if rlp == nil {
+				continue
+			}
+			pending = append(pending, rlp)
+			pendingSize += len(rlp)
+			if pendingSize < p2pTxPacketLimit {
+				continue
+			}
+		}
+		if len(pending) == 0 {
+			continue
+		}
+		req := &sentry.SendMessageByIdRequest{
+			PeerId: peer,
+			Data:   &sentry.OutboundMessageData{Id: messageId, Data: EncodeTransactions(pending, nil)},
+		}
+		if _, err := sentryClient.SendMessageById(f.ctx, req, &grpc.EmptyCallOption{}); err != nil {
+			f.logger.Warn("sentry response", "err", err)
+		}
+		pending, pendingSize = pending[:0], 0
+	}
+}
 
+// sendHashes - sends NEW_POOLED_TRANSACTION_HASHES to the peer, split to messages of p2pTxPacketLimit
+func (f *Send) sendHashes(sentryClient SentryClient, peer PeerID, txs Hashes) {
+	var messageId sentry.MessageId
+	switch sentryClient.Protocol() {
+	case direct.ETH65:
+		messageId = sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_65
+	case direct.ETH66:
+		messageId = sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66
+	default:
+		return
+	}
 	for len(txs) > 0 {
 		var pending Hashes
 		if len(txs) > p2pTxPacketLimit {
@@ -199,40 +221,12 @@ func (f *Send) PropagatePooledTxsToPeersList(peers []PeerID, txs []byte) {
 			txs = txs[:0]
 		}
 
-		data := EncodeHashes(pending, nil)
-		for _, sentryClient := range f.sentryClients {
-			//if !sentryClient.Ready() {
-			//	continue
-			//}
-
-			for _, peer := range peers {
-				switch sentryClient.Protocol() {
-				case direct.ETH65:
-					req65 := &sentry.SendMessageByIdRequest{
-						PeerId: peer,
-						Data: &sentry.OutboundMessageData{
-							Id:   sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_65,
-							Data: data,
-						},
-					}
-
-					if _, err := sentryClient.SendMessageById(f.ctx, req65, &grpc.EmptyCallOption{}); err != nil {
-						f.logger.Warn("sentry response", "err", err)
-					}
-
-				case direct.ETH66:
-					req66 := &sentry.SendMessageByIdRequest{
-						PeerId: peer,
-						Data: &sentry.OutboundMessageData{
-							Id:   sentry.MessageId_NEW_POOLED_TRANSACTION_HASHES_66,
-							Data: data,
-						},
-					}
-					if _, err := sentryClient.SendMessageById(f.ctx, req66, &grpc.EmptyCallOption{}); err != nil {
-						f.logger.Warn("sentry response", "err", err)
-					}
-				}
-			}
+		req := &sentry.SendMessageByIdRequest{
+			PeerId: peer,
+			Data:   &sentry.OutboundMessageData{Id: messageId, Data: EncodeHashes(pending, nil)},
+		}
+		if _, err := sentryClient.SendMessageById(f.ctx, req, &grpc.EmptyCallOption{}); err != nil {
+			f.logger.Warn("sentry response", "err", err)
 		}
 	}
 }
@@ -0,0 +1,184 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces"
+	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
+	"github.com/ledgerwatch/erigon-lib/kv"
+)
+
+// SenderStateProvider loads nonce and balance of transaction senders, which are not known to the pool yet.
+// Implementations may access database or network, so the pool calls them outside of its lock, and
+// transactions of the sender are held back until its state arrives
+type SenderStateProvider interface {
+	SenderState(ctx context.Context, addr [20]byte) (nonce uint64, balance uint256.Int, err error)
+}
+
+// senderStateLoadTimeout - limits time for loading state of one sender
+const senderStateLoadTimeout = 10 * time.Second
+
+// KvSenderStateProvider reads state of senders from kv.PlainState table of the local database
+type KvSenderStateProvider struct {
+	db kv.RoDB
+}
+
+func NewKvSenderStateProvider(db kv.RoDB) *KvSenderStateProvider {
+	return &KvSenderStateProvider{db: db}
+}
+
+func (p *KvSenderStateProvider) SenderState(ctx context.Context, addr [20]byte) (nonce uint64, balance uint256.Int, err error) {
+	err = p.db.View(ctx, func(tx kv.Tx) error {
+		nonce, balance, err = SenderStateFromTx(tx, addr)
+		return err
+	})
+	return nonce, balance, err
+}
+
+// SenderStateFromTx reads nonce and balance of given account from kv.PlainState.
+// Accounts which don't exist yet have zero nonce and balance
+func SenderStateFromTx(tx kv.Tx, addr [20]byte) (nonce uint64, balance uint256.Int, err error) {
+	enc, err := tx.GetOne(kv.PlainState, addr[:])
+	if err != nil {
+		return 0, balance, err
+	}
+	return DecodeSender(enc)
+}
+
+// DecodeSender extracts nonce and balance from account encoded for storage (values of kv.PlainState)
+// Encoding starts with a bitmask of present fields (nonce, balance, incarnation, codeHash),
+// and each present field is prefixed by its length
+func DecodeSender(enc []byte) (nonce uint64, balance uint256.Int, err error) {
+	if len(enc) == 0 {
+		return 0, balance, nil
+	}
+	fieldSet := enc[0]
+	pos := 1
+	if fieldSet&1 > 0 {
+		if pos >= len(enc) {
+			return 0, balance, fmt.Errorf("decode sender: unexpected end of nonce")
+		}
+		decodeLength := int(enc[pos])
+		if decodeLength > 8 || pos+decodeLength+1 > len(enc) {
+			return 0, balance, fmt.Errorf("decode sender: nonce len %d", decodeLength)
+		}
+		for _, b := range enc[pos+1 : pos+decodeLength+1] {
+			nonce = (nonce << 8) | uint64(b)
+		}
+		pos += decodeLength + 1
+	}
+	if fieldSet&2 > 0 {
+		if pos >= len(enc) {
+			return 0, balance, fmt.Errorf("decode sender: unexpected end of balance")
+		}
+		decodeLength := int(enc[pos])
+		if decodeLength > 32 || pos+decodeLength+1 > len(enc) {
+			return 0, balance, fmt.Errorf("decode sender: balance len %d", decodeLength)
+		}
+		balance.SetBytes(enc[pos+1 : pos+decodeLength+1])
+	}
+	return nonce, balance, nil
+}
+
+// RemoteSenderStateProvider requests state of senders from Erigon by TxpoolControl.AccountInfo gRPC
+type RemoteSenderStateProvider struct {
+	client txpool_proto.TxpoolControlClient
+
+	lock         sync.RWMutex
+	blockHash    [32]byte
+	hasBlockHash bool
+}
+
+func NewRemoteSenderStateProvider(client txpool_proto.TxpoolControlClient) *RemoteSenderStateProvider {
+	return &RemoteSenderStateProvider{client: client}
+}
+
+// SetBlockHash - sets block at which state of senders will be requested. Until it's set, the state is
+// requested at the latest block known to Erigon
+func (p *RemoteSenderStateProvider) SetBlockHash(hash [32]byte) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.blockHash, p.hasBlockHash = hash, true
+}
+
+func (p *RemoteSenderStateProvider) SenderState(ctx context.Context, addr [20]byte) (nonce uint64, balance uint256.Int, err error) {
+	req := &txpool_proto.AccountInfoRequest{Account: gointerfaces.ConvertAddressToH160(addr)}
+	p.lock.RLock()
+	if p.hasBlockHash {
+		req.BlockHash = gointerfaces.ConvertHashToH256(p.blockHash)
+	}
+	p.lock.RUnlock()
+	reply, err := p.client.AccountInfo(ctx, req)
+	if err != nil {
+		return 0, balance, err
+	}
+	if reply.Balance != nil {
+		balance = *gointerfaces.ConvertH256ToUint256Int(reply.Balance)
+	}
+	return reply.Nonce, balance, nil
+}
+
+// sendersRegistry - compact ids of senders known to the pool, which are used instead of 20-byte addresses as
+// keys of sender-related structures. Ids are allocated by monotonic counter, so id of a forgotten sender is never
+// given to another one - and stale ids can't be confused with fresh ones
+type sendersRegistry struct {
+	ids    map[string]uint64 // address => id
+	addrs  map[uint64]string // id => address
+	lastID uint64
+}
+
+func newSendersRegistry() *sendersRegistry {
+	return &sendersRegistry{ids: map[string]uint64{}, addrs: map[uint64]string{}}
+}
+
+func (r *sendersRegistry) id(addr string) (uint64, bool) {
+	id, ok := r.ids[addr]
+	return id, ok
+}
+
+// addr - 20-byte address of sender, nil if id is unknown
+func (r *sendersRegistry) addr(id uint64) []byte {
+	addr, ok := r.addrs[id]
+	if !ok {
+		return nil
+	}
+	return []byte(addr)
+}
+
+func (r *sendersRegistry) getOrCreateID(addr string) uint64 {
+	id, ok := r.ids[addr]
+	if !ok {
+		r.lastID++
+		id = r.lastID
+		r.ids[addr] = id
+		r.addrs[id] = addr
+	}
+	return id
+}
+
+func (r *sendersRegistry) remove(id uint64) {
+	delete(r.ids, r.addrs[id])
+	delete(r.addrs, id)
+}
+
+func (r *sendersRegistry) len() int { return len(r.ids) }
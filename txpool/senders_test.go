@@ -0,0 +1,73 @@
+/*
+   Copyright 2021 Erigon contributors
+
+   Licensed under the Apache License, Version 2.0 (the "License");
+   you may not use this file except in compliance with the License.
+   You may obtain a copy of the License at
+
+       http://www.apache.org/licenses/LICENSE-2.0
+
+   Unless required by applicable law or agreed to in writing, software
+   distributed under the License is distributed on an "AS IS" BASIS,
+   WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+   See the License for the specific language governing permissions and
+   limitations under the License.
+*/
+
+package txpool
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+)
+
+var senderDecodeTests = []struct {
+	encStr      string
+	nonce       uint64
+	balance     uint64
+	expectedErr bool
+}{
+	{encStr: "", nonce: 0, balance: 0},
+	{encStr: "03010502012c", nonce: 5, balance: 300},
+	{encStr: "0202012c", nonce: 0, balance: 300},
+	// incarnation and code hash are ignored
+	{encStr: "0f01050201000101200000000000000000000000000000000000000000000000000000000000000000", nonce: 5, balance: 256},
+	{encStr: "0301", expectedErr: true},
+	{encStr: "0109000000000000000001", expectedErr: true},
+}
+
+func TestDecodeSender(t *testing.T) {
+	for i, tt := range senderDecodeTests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			require := require.New(t)
+			nonce, balance, err := DecodeSender(decodeHex(tt.encStr))
+			require.Equal(tt.expectedErr, err != nil)
+			if err != nil {
+				return
+			}
+			require.Equal(tt.nonce, nonce)
+			require.Equal(*uint256.NewInt(tt.balance), balance)
+		})
+	}
+}
+
+func TestSendersRegistry(t *testing.T) {
+	require := require.New(t)
+	r := newSendersRegistry()
+	id1 := r.getOrCreateID("a")
+	id2 := r.getOrCreateID("b")
+	require.NotEqual(id1, id2)
+	require.Equal(id1, r.getOrCreateID("a"))
+	require.Equal([]byte("b"), r.addr(id2))
+
+	// ids of forgotten senders are not reused
+	r.remove(id1)
+	require.Nil(r.addr(id1))
+	_, ok := r.id("a")
+	require.False(ok)
+	require.Equal(1, r.len())
+	require.Greater(r.getOrCreateID("a"), id2)
+}
@@ -18,6 +18,7 @@ package txpool
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"hash"
 	"io"
@@ -48,6 +49,10 @@ type TxParseContext struct {
 	buf           [65]byte // buffer needs to be enough for hashes (32 bytes) and for public key (65 bytes)
 	sighash       [32]byte
 	sig           [65]byte
+
+	cfgChainID   uint256.Int // transactions signed for other chains are rejected, if checkChainID is set
+	checkChainID bool
+	known        func(idHash []byte) bool // if set, parsing of known transactions stops after computing idHash
 }
 
 func NewTxParseContext() *TxParseContext {
@@ -62,11 +67,19 @@ func NewTxParseContext() *TxParseContext {
 	return ctx
 }
 
+// WithChainID - makes ParseTransaction reject transactions signed for other chains. Legacy transactions
+// without replay protection (EIP-155) don't have chainId and are accepted. Zero chainID disables the check
+func (ctx *TxParseContext) WithChainID(chainID uint256.Int) *TxParseContext {
+	ctx.cfgChainID, ctx.checkChainID = chainID, !chainID.IsZero()
+	return ctx
+}
+
 // TxSlot contains information extracted from an Ethereum transaction, which is enough to manage it inside the transaction.
 // Also, it contains some auxillary information, like ephemeral fields, and indices within priority queues
 type TxSlot struct {
 	//txId        uint64      // Transaction id (distinct from transaction hash), used as a compact reference to a transaction accross data structures
 	//senderId    uint64      // Sender id (distinct from sender address), used as a compact referecne to to a sender accross data structures
+	txType      int         // LegacyTxType, AccessListTxType or DynamicFeeTxType
 	nonce       uint64      // Nonce of the transaction
 	tip         uint64      // Maximum tip that transaction is giving to miner/block proposer
 	feeCap      uint64      // Maximum fee that transaction burns and gives to the miner/block proposer
@@ -75,7 +88,9 @@ type TxSlot struct {
 	idHash      [32]byte    // Transaction hash for the purposes of using it as a transaction Id
 	senderID    uint64      // SenderID - require external mapping to it's address
 	creation    bool        // Set to true if "To" field of the transation is not set
+	to          [20]byte    // Recipient of the transaction, zero if creation
 	dataLen     int         // Length of transaction's data (for calculation of intrinsic gas)
+	dataNonZero int         // Number of non-zero bytes in transaction's data (for calculation of intrinsic gas)
 	alAddrCount int         // Number of addresses in the access list
 	alStorCount int         // Number of storage keys in the access list
 	//bestIdx     int         // Index of the transaction in the best priority queue (of whatever pool it currently belongs to)
@@ -85,10 +100,44 @@ type TxSlot struct {
 	rlp []byte
 }
 
+// Accessors of TxSlot fields, for code outside of the package, like AdmissionPolicy
+func (tx *TxSlot) Type() int          { return tx.txType }
+func (tx *TxSlot) Hash() [32]byte     { return tx.idHash }
+func (tx *TxSlot) Nonce() uint64      { return tx.nonce }
+func (tx *TxSlot) Tip() uint64        { return tx.tip }
+func (tx *TxSlot) FeeCap() uint64     { return tx.feeCap }
+func (tx *TxSlot) Gas() uint64        { return tx.gas }
+func (tx *TxSlot) Value() uint256.Int { return tx.value }
+func (tx *TxSlot) Creation() bool     { return tx.creation }
+func (tx *TxSlot) To() [20]byte       { return tx.to }
+func (tx *TxSlot) DataLen() int       { return tx.dataLen }
+
 type TxSlots struct {
 	txs     []*TxSlot
 	senders []byte // plain 20-byte addresses
 	isLocal []bool
+	private []bool // see AppendPrivate
+}
+
+// Append adds transaction slot, its 20-byte sender address and locality flag to the end of the batch
+func (s *TxSlots) Append(slot *TxSlot, sender []byte, isLocal bool) {
+	s.txs = append(s.txs, slot)
+	s.senders = append(s.senders, sender...)
+	s.isLocal = append(s.isLocal, isLocal)
+	s.private = append(s.private, false)
+}
+
+// AppendPrivate adds local transaction, which is never sent to peers: for example, meant only for own block
+// builder. See TxPoolConfig.PrivateTxBlocks
+func (s *TxSlots) AppendPrivate(slot *TxSlot, sender []byte) {
+	s.Append(slot, sender, true)
+	s.private[len(s.private)-1] = true
+}
+
+// appendFrom adds i-th transaction of the batch from, with all its flags
+func (s *TxSlots) appendFrom(from *TxSlots, i int) {
+	s.Append(from.txs[i], from.senders[i*20:(i+1)*20], from.isLocal[i])
+	s.private[len(s.private)-1] = from.private[i]
 }
 
 const (
@@ -99,13 +148,62 @@ const (
 
 const ParseTransactionErrorPrefix = "parse transaction payload"
 
+// Errors of ParseTransaction, which are not about encoding: transaction is well-formed, but invalid. They are
+// returned wrapped, use errors.Is to check them
+var (
+	ErrInvalidChainID = errors.New("invalid chainId")
+	ErrHighS          = errors.New("signature S value is greater than secp256k1n/2 (EIP-2)")
+	// ErrAlreadyKnown - transaction is known to the pool (see TxParseContext.known), returned slot has only
+	// idHash and rlp, sender is not recovered
+	ErrAlreadyKnown = errors.New("already known")
+)
+
+// secp256k1halfN - signatures with bigger S value are malleable, they are not accepted since Homestead
+var secp256k1halfN = &uint256.Int{0xdfe92f46681b20a0, 0x5d576e7357a4501d, 0xffffffffffffffff, 0x7fffffffffffffff}
+
+// wrapTypedTx - EIP-2718 transaction in canonical encoding (type byte followed by RLP list), as users send it,
+// is wrapped into RLP string: encoding of p2p messages, which ParseTransaction expects. Other payloads (legacy
+// transactions, already wrapped ones) are returned as is
+func wrapTypedTx(payload []byte) []byte {
+	if len(payload) == 0 || payload[0] >= 0x80 {
+		return payload
+	}
+	wrapped := make([]byte, rlp.ListPrefixLen(len(payload))+len(payload)) // string prefix has the same length
+	rlp.EncodeString(payload, wrapped)
+	return wrapped
+}
+
+// unwrapTypedTx - canonical encoding of the transaction parsed by ParseTransaction, opposite of wrapTypedTx
+func unwrapTypedTx(rlpTx []byte) []byte {
+	dataPos, dataLen, isList, err := rlp.Prefix(rlpTx, 0)
+	if err != nil || isList || dataPos+dataLen > len(rlpTx) {
+		return rlpTx
+	}
+	return rlpTx[dataPos : dataPos+dataLen]
+}
+
+// ParseSingleTransaction - ParseTransaction of payload which holds exactly one transaction, as received from
+// users or read from db: bytes after the transaction are rejected, they are not covered by its hash
+func (ctx *TxParseContext) ParseSingleTransaction(payload []byte) (slot *TxSlot, sender [20]byte, err error) {
+	slot, sender, p, err := ctx.ParseTransaction(payload, 0)
+	if err != nil {
+		return nil, sender, err
+	}
+	if p != len(payload) {
+		return nil, sender, fmt.Errorf("%s: extraneous data after transaction", ParseTransactionErrorPrefix)
+	}
+	return slot, sender, nil
+}
+
 // ParseTransaction extracts all the information from the transactions's payload (RLP) necessary to build TxSlot
 // it also performs syntactic validation of the transactions
+// Transaction is parsed starting from given position, which allows to parse transactions which are elements of
+// an RLP list (as in TRANSACTIONS and POOLED_TRANSACTIONS messages). Returned position points right after
+// the transaction, and rlp field of the slot references the transaction's encoding inside the payload
 func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSlot, sender [20]byte, p int, err error) {
-	if len(payload) == 0 {
+	if len(payload) <= pos {
 		return nil, sender, 0, fmt.Errorf("%s: empty rlp", ParseTransactionErrorPrefix)
 	}
-	slot = &TxSlot{rlp: payload}
 	// Compute transaction hash
 	ctx.keccak1.Reset()
 	ctx.keccak2.Reset()
@@ -115,15 +213,21 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 	if err != nil {
 		return nil, sender, 0, fmt.Errorf("%s: size Prefix: %v", ParseTransactionErrorPrefix, err)
 	}
-	if dataPos+dataLen != len(payload) {
-		return nil, sender, 0, fmt.Errorf("%s: transaction must be either 1 list or 1 string", ParseTransactionErrorPrefix)
+	end := dataPos + dataLen
+	if end > len(payload) {
+		return nil, sender, 0, fmt.Errorf("%s: unexpected end of payload", ParseTransactionErrorPrefix)
 	}
+	slot = &TxSlot{rlp: payload[pos:end]}
 	p = dataPos
 
 	var txType int
 	// If it is non-legacy transaction, the transaction type follows, and then the the list
 	if !legacy {
+		if p >= end {
+			return nil, sender, 0, fmt.Errorf("%s: unexpected end of payload before txType", ParseTransactionErrorPrefix)
+		}
 		txType = int(payload[p])
+		slot.txType = txType
 		if _, err = ctx.keccak1.Write(payload[p : p+1]); err != nil {
 			return nil, sender, 0, fmt.Errorf("%s: computing idHash (hashing type Prefix): %w", ParseTransactionErrorPrefix, err)
 		}
@@ -131,13 +235,16 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 			return nil, sender, 0, fmt.Errorf("%s: computing signHash (hashing type Prefix): %w", ParseTransactionErrorPrefix, err)
 		}
 		p++
-		if p >= len(payload) {
+		if p >= end {
 			return nil, sender, 0, fmt.Errorf("%s: unexpected end of payload after txType", ParseTransactionErrorPrefix)
 		}
 		dataPos, dataLen, err = rlp.List(payload, p)
 		if err != nil {
 			return nil, sender, 0, fmt.Errorf("%s: envelope Prefix: %v", ParseTransactionErrorPrefix, err)
 		}
+		if dataPos+dataLen != end {
+			return nil, sender, 0, fmt.Errorf("%s: transaction must be either 1 list or 1 string", ParseTransactionErrorPrefix)
+		}
 		// Hash the envelope, not the full payload
 		if _, err = ctx.keccak1.Write(payload[p : dataPos+dataLen]); err != nil {
 			return nil, sender, 0, fmt.Errorf("%s: computing idHash (hashing the envelope): %w", ParseTransactionErrorPrefix, err)
@@ -146,13 +253,15 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 	}
 	// Remember where signing hash data begins (it will need to be wrapped in an RLP list)
 	sigHashPos := p
-	// If it is non-legacy tx, chainId follows, but we skip it
+	// If it is non-legacy tx, chainId follows
 	if !legacy {
-		dataPos, dataLen, err = rlp.String(payload, p)
+		p, err = rlp.U256(payload, p, &ctx.chainId)
 		if err != nil {
 			return nil, sender, 0, fmt.Errorf("%s: chainId len: %w", ParseTransactionErrorPrefix, err)
 		}
-		p = dataPos + dataLen
+		if ctx.checkChainID && !ctx.chainId.Eq(&ctx.cfgChainID) {
+			return nil, sender, 0, fmt.Errorf("%s: %w: %d", ParseTransactionErrorPrefix, ErrInvalidChainID, &ctx.chainId)
+		}
 	}
 	// Next follows the nonce, which we need to parse
 	p, slot.nonce, err = rlp.U64(payload, p)
@@ -189,8 +298,8 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 	if dataLen != 0 && dataLen != 20 {
 		return nil, sender, 0, fmt.Errorf("%s: unexpected length of to field: %d", ParseTransactionErrorPrefix, dataLen)
 	}
-	// Only note if To field is empty or not
 	slot.creation = dataLen == 0
+	copy(slot.to[:], payload[dataPos:dataPos+dataLen])
 	p = dataPos + dataLen
 	// Next follows value
 	p, err = rlp.U256(payload, p, &slot.value)
@@ -203,6 +312,11 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 		return nil, sender, 0, fmt.Errorf("%s: data len: %w", ParseTransactionErrorPrefix, err)
 	}
 	slot.dataLen = dataLen
+	for _, b := range payload[dataPos : dataPos+dataLen] {
+		if b != 0 {
+			slot.dataNonZero++
+		}
+	}
 	p = dataPos + dataLen
 	// Next follows access list for non-legacy transactions, we are only interesting in number of addresses and storage keys
 	if !legacy {
@@ -275,6 +389,9 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 			}
 			sigHashLen += uint(chainIdLen) // For chainId
 			sigHashLen += 2                // For two extra zeros
+			if ctx.checkChainID && !ctx.chainId.Eq(&ctx.cfgChainID) {
+				return nil, sender, 0, fmt.Errorf("%s: %w: %d", ParseTransactionErrorPrefix, ErrInvalidChainID, &ctx.chainId)
+			}
 		}
 	} else {
 		var v uint64
@@ -297,6 +414,12 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 	if err != nil {
 		return nil, sender, 0, fmt.Errorf("%s: S: %w", ParseTransactionErrorPrefix, err)
 	}
+	if p != end {
+		return nil, sender, 0, fmt.Errorf("%s: extraneous space after signature", ParseTransactionErrorPrefix)
+	}
+	if ctx.s.Gt(secp256k1halfN) {
+		return nil, sender, 0, fmt.Errorf("%s: %w", ParseTransactionErrorPrefix, ErrHighS)
+	}
 	// For legacy transactions, hash the full payload
 	if legacy {
 		if _, err = ctx.keccak1.Write(payload[pos:p]); err != nil {
@@ -305,6 +428,9 @@ func (ctx *TxParseContext) ParseTransaction(payload []byte, pos int) (slot *TxSl
 	}
 	//ctx.keccak1.Sum(slot.idHash[:0])
 	_, _ = ctx.keccak1.(io.Reader).Read(slot.idHash[:32])
+	if ctx.known != nil && ctx.known(slot.idHash[:]) {
+		return slot, sender, p, fmt.Errorf("%s: %w", ParseTransactionErrorPrefix, ErrAlreadyKnown)
+	}
 	// Computing sigHash (hash used to recover sender from the signature)
 	// Write len Prefix to the sighash
 	if sigHashLen < 56 {
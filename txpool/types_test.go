@@ -21,6 +21,7 @@ import (
 	"fmt"
 	"testing"
 
+	"github.com/holiman/uint256"
 	"github.com/stretchr/testify/require"
 )
 
@@ -88,3 +89,41 @@ func TestParseTransactionRLP(t *testing.T) {
 		})
 	}
 }
+
+func TestParseTransactionValidation(t *testing.T) {
+	require := require.New(t)
+	ctx := NewTxParseContext().WithChainID(*uint256.NewInt(1))
+	// txParseTests[1] is EIP-155 legacy transaction and txParseTests[2] is dynamic fee transaction, both of chain 123
+	for _, i := range []int{1, 2} {
+		_, _, _, err := ctx.ParseTransaction(decodeHex(txParseTests[i].payloadStr), 0)
+		require.ErrorIs(err, ErrInvalidChainID)
+	}
+	// legacy transaction without replay protection
+	_, _, _, err := ctx.ParseTransaction(decodeHex(txParseTests[0].payloadStr), 0)
+	require.NoError(err)
+
+	ctx = NewTxParseContext().WithChainID(*uint256.NewInt(123))
+	for _, i := range []int{0, 1, 2} {
+		_, _, _, err = ctx.ParseTransaction(decodeHex(txParseTests[i].payloadStr), 0)
+		require.NoError(err)
+	}
+
+	// same signature with S = secp256k1n - S is also valid, but only low S is accepted
+	payload := decodeHex(txParseTests[0].payloadStr)
+	n := uint256.Int{0xbfd25e8cd0364141, 0xbaaedce6af48a03b, 0xfffffffffffffffe, 0xffffffffffffffff}
+	s := new(uint256.Int).SetBytes(payload[len(payload)-32:])
+	highS := new(uint256.Int).Sub(&n, s).Bytes32()
+	copy(payload[len(payload)-32:], highS[:])
+	_, _, _, err = ctx.ParseTransaction(payload, 0)
+	require.ErrorIs(err, ErrHighS)
+
+	// transaction may be followed by other list elements, but not when it's the whole payload
+	payload = append(decodeHex(txParseTests[2].payloadStr), 0x80)
+	_, _, p, err := ctx.ParseTransaction(payload, 0)
+	require.NoError(err)
+	require.Equal(len(payload)-1, p)
+	_, _, err = ctx.ParseSingleTransaction(payload)
+	require.Error(err)
+	_, _, err = ctx.ParseSingleTransaction(payload[:len(payload)-1])
+	require.NoError(err)
+}